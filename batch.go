@@ -0,0 +1,134 @@
+package structcheck
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Validates many values concurrently. Workers share one CheckFinder and one set of options, which are built once per call to Validate or ValidateChan; tags and field masks are parsed once per distinct tag and type.
+type Batch struct {
+	Finder  CheckFinder // nil means BuildTagCheckFinder(DefaultChecks), with DefaultChecks read once per batch
+	Options []Option
+	Workers int // maximum concurrent validations. 0 means runtime.GOMAXPROCS(0).
+}
+
+// the outcome of validating one item of a batch
+type ItemResult struct {
+	Index int
+	Valid bool
+	Err   error // ErrorChecksFailed if the item failed checks, or the error that kept it from being validated
+}
+
+// aggregate statistics for a batch
+type BatchSummary struct {
+	Total   int
+	Valid   int
+	Invalid int            // items that failed checks
+	Errored int            // items that couldn't be validated
+	ByCheck map[string]int // failing fields per check name
+	ByPath  map[string]int // failing fields per path, with element indices replaced by [*]
+}
+
+type BatchResult struct {
+	Items   []ItemResult // in input order
+	Summary BatchSummary
+}
+
+// Validates every element of items, which must be a slice or array
+func (b Batch) Validate(items interface{}) (BatchResult, error) {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return BatchResult{}, fmt.Errorf("Batch.Validate requires a slice or array. Received: %T", items)
+	}
+	ch := make(chan interface{})
+	go func() {
+		for i := 0; i < v.Len(); i++ {
+			ch <- v.Index(i).Interface()
+		}
+		close(ch)
+	}()
+	return b.ValidateChan(ch), nil
+}
+
+// Validates every value received from items until it is closed
+func (b Batch) ValidateChan(items <-chan interface{}) BatchResult {
+	finder := b.Finder
+	if finder == nil {
+		finder = buildCachedTagCheckFinder(DefaultChecks)
+	}
+	o := newOptions(b.Options)
+	o.maskChecked = &sync.Map{}
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	type job struct {
+		index int
+		item  interface{}
+	}
+	jobs := make(chan job)
+	go func() {
+		index := 0
+		for item := range items {
+			jobs <- job{index: index, item: item}
+			index++
+		}
+		close(jobs)
+	}()
+
+	results := []ItemResult{}
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				err := o.err
+				if err == nil {
+					err = validate(j.item, finder, o)
+				}
+				mu.Lock()
+				results = append(results, ItemResult{Index: j.index, Valid: err == nil, Err: err})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Index < results[j].Index
+	})
+	return BatchResult{Items: results, Summary: summarize(results)}
+}
+
+var elementIndex = regexp.MustCompile(`\[[^\]]*\]`)
+
+func summarize(results []ItemResult) BatchSummary {
+	s := BatchSummary{
+		Total:   len(results),
+		ByCheck: make(map[string]int),
+		ByPath:  make(map[string]int),
+	}
+	for _, r := range results {
+		checksFailed, ok := r.Err.(ErrorChecksFailed)
+		switch {
+		case r.Valid:
+			s.Valid++
+		case ok:
+			s.Invalid++
+			for field, checks := range checksFailed.Field2Checks {
+				s.ByPath[elementIndex.ReplaceAllString(field.Path, "[*]")]++
+				for _, check := range checks {
+					s.ByCheck[check]++
+				}
+			}
+		default:
+			s.Errored++
+		}
+	}
+	return s
+}
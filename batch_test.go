@@ -0,0 +1,93 @@
+package structcheck
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type batchTestRecord struct {
+	ID    *int `checks:"NotNil"`
+	Lines []struct {
+		Qty int `checks:"Positive"`
+	}
+}
+
+func TestBatch_Validate(t *testing.T) {
+	one := 1
+	records := make([]interface{}, 100)
+	for i := range records {
+		r := batchTestRecord{ID: &one}
+		if i%10 == 0 {
+			r.ID = nil
+		}
+		r.Lines = make([]struct {
+			Qty int `checks:"Positive"`
+		}, 2)
+		r.Lines[0].Qty = 1
+		r.Lines[1].Qty = i % 4
+		records[i] = r
+	}
+	records[50] = "not a struct"
+	result, err := Batch{Workers: 4}.Validate(records)
+	require.NoError(t, err)
+	require.Len(t, result.Items, 100)
+	for i, item := range result.Items {
+		require.Equal(t, i, item.Index)
+	}
+	require.True(t, result.Items[1].Valid)
+	require.False(t, result.Items[4].Valid)
+	require.IsType(t, ErrorInvalidKind{}, result.Items[50].Err)
+	require.Equal(t, BatchSummary{
+		Total:   100,
+		Valid:   70,
+		Invalid: 29,
+		Errored: 1,
+		ByCheck: map[string]int{"NotNil": 9, "Positive": 25},
+		ByPath:  map[string]int{"ID": 9, "Lines[*].Qty": 25},
+	}, result.Summary)
+}
+
+func TestBatch_ValidateChan(t *testing.T) {
+	ch := make(chan interface{})
+	go func() {
+		ch <- batchTestRecord{}
+		ch <- batchTestRecord{ID: new(int)}
+		close(ch)
+	}()
+	result := Batch{}.ValidateChan(ch)
+	require.Equal(t, 2, result.Summary.Total)
+	require.False(t, result.Items[0].Valid)
+	require.True(t, result.Items[1].Valid)
+}
+
+func TestBatch_notSlice(t *testing.T) {
+	_, err := Batch{}.Validate(batchTestRecord{})
+	require.Error(t, err)
+}
+
+func TestBatch_sharedPlan(t *testing.T) {
+	built := 0
+	countBuilds := func(o *options) {
+		built++
+	}
+	records := make([]batchTestRecord, 20)
+	result, err := Batch{Workers: 4, Options: []Option{countBuilds, WithGroups("strict")}}.Validate(records)
+	require.NoError(t, err)
+	require.Equal(t, 1, built)
+	require.Equal(t, 20, result.Summary.Invalid)
+
+	// illegal checks are still reported for every item, but only in active groups
+	type illegal struct {
+		Name string `checks:"NotAThing@other"`
+		Qty  int    `checks:"Positive,Bogus@strict"`
+	}
+	result, err = Batch{Options: []Option{WithGroups("strict")}}.Validate([]illegal{{}, {}})
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Summary.Errored)
+	require.EqualError(t, result.Items[1].Err, "Encountered illegal check on illegal.Qty: 'Bogus' is not a recognized check type")
+
+	// invalid options fail every item
+	result, err = Batch{Options: []Option{WithFieldMask("Nope")}}.Validate(records[:3])
+	require.NoError(t, err)
+	require.Equal(t, 3, result.Summary.Errored)
+}
@@ -3,6 +3,8 @@ package structcheck
 import (
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 // returns true if the check is met
@@ -30,6 +32,19 @@ var DefaultChecks = map[string]Check{
 	"Empty": func(v reflect.Value) bool {
 		return !(Container.Check(v) && v.Len() != 0)
 	},
+	"NotZero": func(v reflect.Value) bool {
+		return !v.IsZero()
+	},
+	"Zero": func(v reflect.Value) bool {
+		return v.IsZero()
+	},
+	// without presence information (see WithPresence), Required and Forbidden treat zero values as absent
+	"Required": func(v reflect.Value) bool {
+		return !v.IsZero()
+	},
+	"Forbidden": func(v reflect.Value) bool {
+		return v.IsZero()
+	},
 	"Nilable": func(v reflect.Value) bool {
 		return Nilable.Check(v)
 	},
@@ -41,6 +56,75 @@ var DefaultChecks = map[string]Check{
 	},
 }
 
+// Parameterized checks, written as Name(param) wherever check names are accepted (e.g. `checks:"MaxLen(10)"`)
+var DefaultParamChecks = map[string]func(param string) (Check, error){
+	"MinLen": func(param string) (Check, error) {
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return nil, fmt.Errorf("MinLen requires an integer length: %v", err)
+		}
+		return func(v reflect.Value) bool {
+			return !(Container.Check(v) && v.Len() < n)
+		}, nil
+	},
+	"MaxLen": func(param string) (Check, error) {
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return nil, fmt.Errorf("MaxLen requires an integer length: %v", err)
+		}
+		return func(v reflect.Value) bool {
+			return !(Container.Check(v) && v.Len() > n)
+		}, nil
+	},
+}
+
+// Checks that look at a pointer itself by default. All other checks look at the value at the end of a chain of pointers.
+var PointerChecks = map[string]interface{}{
+	"NotNil":    nil,
+	"Nil":       nil,
+	"Nilable":   nil,
+	"Required":  nil,
+	"Forbidden": nil,
+}
+
+// which node of a pointer chain a check runs on
+type checkLevel int
+
+const (
+	levelDefault checkLevel = iota // pointer level for PointerChecks, otherwise elem level skipping nil pointers
+	levelPointer                   // ptr:Name
+	levelElem                      // elem:Name, fails on nil pointers
+)
+
+// splits a ptr: or elem: prefix off of a check expression
+func splitCheckLevel(expr string) (checkLevel, string) {
+	switch {
+	case strings.HasPrefix(expr, "ptr:"):
+		return levelPointer, expr[len("ptr:"):]
+	case strings.HasPrefix(expr, "elem:"):
+		return levelElem, expr[len("elem:"):]
+	}
+	if _, ok := PointerChecks[expr]; ok {
+		return levelPointer, expr
+	}
+	return levelDefault, expr
+}
+
+// Resolves a check name from checkSet, or a Name(param) expression from DefaultParamChecks. Either may have a ptr: or elem: prefix. Returns a nil Check if no check matches.
+func lookupCheck(expr string, checkSet map[string]Check) (Check, error) {
+	_, expr = splitCheckLevel(expr)
+	if check, ok := checkSet[expr]; ok {
+		return check, nil
+	}
+	open := strings.Index(expr, "(")
+	if open > 0 && strings.HasSuffix(expr, ")") {
+		if build, ok := DefaultParamChecks[expr[:open]]; ok {
+			return build(expr[open+1 : len(expr)-1])
+		}
+	}
+	return nil, nil
+}
+
 type KindClass map[reflect.Kind]interface{}
 
 // true if v is a member of this class
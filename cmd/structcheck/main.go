@@ -0,0 +1,500 @@
+/*
+Command structcheck validates JSON documents against a constraint file.
+
+Usage:
+
+	structcheck -constraints FILE [-output human|json|junit] [-ndjson] DOCUMENT...
+
+The constraint file lists one path pattern per line, followed by a colon and a comma-separated list of checks. Patterns use the syntax of structcheck.BuildStringyCheckFinder, with object keys acting as field names; blank lines and lines starting with # are ignored:
+
+	# every item needs a positive ID
+	items[*].id: Positive
+	name:        NotEmpty
+
+Documents named "-" are read from standard input. Files ending in .ndjson or .jsonl (or every document, with -ndjson) hold one JSON record per line and are validated record by record. Only values present in a document are checked, except by Required, which fails if a key named by its pattern is absent: "items[*].id: Required" requires an items key and an id in every item, while * and [*] only match the keys and elements that are there. Required patterns can't use ** or type segments.
+
+The exit status is 0 if every document is valid, 1 if any failed checks and 2 if a document or the constraint file couldn't be read.
+*/
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"github.com/Manbeardo/structcheck"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("structcheck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	constraintsFile := fs.String("constraints", "", "file of path patterns and their checks")
+	output := fs.String("output", "human", "output format: human, json or junit")
+	ndjson := fs.Bool("ndjson", false, "treat every document as newline-delimited JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *constraintsFile == "" || fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: structcheck -constraints FILE [-output human|json|junit] [-ndjson] DOCUMENT...")
+		return 2
+	}
+	f, err := os.Open(*constraintsFile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	constraints, err := parseConstraints(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(stderr, "%v: %v\n", *constraintsFile, err)
+		return 2
+	}
+	required, err := splitRequired(constraints)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v: %v\n", *constraintsFile, err)
+		return 2
+	}
+	finder, err := structcheck.BuildStringyCheckFinder(constraints, structcheck.DefaultChecks)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v: %v\n", *constraintsFile, err)
+		return 2
+	}
+	v := validator{finder: finder, required: required}
+	var rep reporter
+	switch *output {
+	case "human":
+		rep = &humanReporter{w: stdout}
+	case "json":
+		rep = &jsonReporter{enc: json.NewEncoder(stdout)}
+	case "junit":
+		rep = &junitReporter{w: stdout}
+	default:
+		fmt.Fprintf(stderr, "unknown output format: %v\n", *output)
+		return 2
+	}
+
+	status := 0
+	for _, name := range fs.Args() {
+		var r io.ReadCloser = ioutil.NopCloser(stdin)
+		if name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				status = 2
+				continue
+			}
+			r = f
+		}
+		lines := *ndjson || strings.HasSuffix(name, ".ndjson") || strings.HasSuffix(name, ".jsonl")
+		failed, err := v.validateDocument(name, r, lines, rep)
+		r.Close()
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			status = 2
+		} else if failed && status == 0 {
+			status = 1
+		}
+		if err := rep.EndDocument(); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+	if err := rep.Flush(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return status
+}
+
+// reads "pattern: Check1, Check2" lines
+func parseConstraints(r io.Reader) (map[string][]string, error) {
+	constraints := make(map[string][]string)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		colon := strings.LastIndex(line, ":")
+		if colon == -1 {
+			return nil, fmt.Errorf("line %d: expected 'path: checks'", lineNum)
+		}
+		path := strings.TrimSpace(line[:colon])
+		for _, check := range strings.Split(line[colon+1:], ",") {
+			if check = strings.TrimSpace(check); check != "" {
+				constraints[path] = append(constraints[path], check)
+			}
+		}
+	}
+	return constraints, scanner.Err()
+}
+
+// Removes the Required checks from constraints, returning the parsed paths that must be present. Paths that have no other checks are removed.
+func splitRequired(constraints map[string][]string) ([][]string, error) {
+	patterns := []string{}
+	for pattern, checks := range constraints {
+		rest := []string{}
+		for _, check := range checks {
+			if check != "Required" {
+				rest = append(rest, check)
+			}
+		}
+		if len(rest) == len(checks) {
+			continue
+		}
+		patterns = append(patterns, pattern)
+		if len(rest) == 0 {
+			delete(constraints, pattern)
+		} else {
+			constraints[pattern] = rest
+		}
+	}
+	sort.Strings(patterns)
+	required := make([][]string, len(patterns))
+	for i, pattern := range patterns {
+		segments, err := parseRequiredPath(pattern)
+		if err != nil {
+			return nil, err
+		}
+		required[i] = segments
+	}
+	return required, nil
+}
+
+// splits a Required pattern into object keys, * and array indexes ([N] or [*])
+func parseRequiredPath(pattern string) ([]string, error) {
+	segments := []string{}
+	for _, part := range strings.Split(pattern, ".") {
+		name, indexes := part, ""
+		if open := strings.Index(part, "["); open != -1 {
+			name, indexes = part[:open], part[open:]
+		}
+		if name == "**" || strings.HasPrefix(name, "(") {
+			return nil, fmt.Errorf("%v: Required patterns can't use ** or type segments", pattern)
+		}
+		if name != "" {
+			segments = append(segments, name)
+		}
+		for indexes != "" {
+			end := strings.Index(indexes, "]")
+			if !strings.HasPrefix(indexes, "[") || end == -1 {
+				return nil, fmt.Errorf("%v: malformed index", pattern)
+			}
+			index := indexes[1:end]
+			if _, err := strconv.Atoi(index); err != nil && index != "*" {
+				return nil, fmt.Errorf("%v: Required patterns only index arrays", pattern)
+			}
+			segments = append(segments, indexes[:end+1])
+			indexes = indexes[end+1:]
+		}
+	}
+	return segments, nil
+}
+
+// appends the paths below doc (at path) that segments require but doc doesn't have
+func findAbsent(doc interface{}, path string, segments []string, absent []string) []string {
+	if len(segments) == 0 {
+		return absent
+	}
+	segment, rest := segments[0], segments[1:]
+	switch {
+	case segment == "[*]":
+		if list, ok := doc.([]interface{}); ok {
+			for i, elem := range list {
+				absent = findAbsent(elem, fmt.Sprintf("%v[%d]", path, i), rest, absent)
+			}
+		}
+	case strings.HasPrefix(segment, "["):
+		i, _ := strconv.Atoi(segment[1 : len(segment)-1])
+		if list, ok := doc.([]interface{}); ok && i < len(list) {
+			return findAbsent(list[i], path+segment, rest, absent)
+		}
+		return append(absent, path+segment)
+	case segment == "*":
+		if object, ok := doc.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(object))
+			for key := range object {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				absent = findAbsent(object[key], joinPath(path, key), rest, absent)
+			}
+		}
+	default:
+		object, _ := doc.(map[string]interface{})
+		value, ok := object[segment]
+		if !ok {
+			return append(absent, joinPath(path, segment))
+		}
+		return findAbsent(value, joinPath(path, segment), rest, absent)
+	}
+	return absent
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// a record's failed checks
+type result struct {
+	Source string // document name, plus the line number of NDJSON records
+	Name   string // document name
+	Record int    // line number of NDJSON records, 0 otherwise
+	Fields []structcheck.Field
+	Checks map[structcheck.Field][]string
+	Absent map[structcheck.Field]bool // fields that failed Required because they're missing
+}
+
+// the value of a failed field as reporters print it
+func (r result) value(field structcheck.Field) string {
+	if r.Absent[field] {
+		return "absent"
+	}
+	return field.Value
+}
+
+type reporter interface {
+	Report(r result) error
+	EndDocument() error // called after the last result of each document
+	Flush() error
+}
+
+// validates documents against the constraint file
+type validator struct {
+	finder   structcheck.CheckFinder
+	required [][]string // paths that must be present (see parseRequiredPath)
+}
+
+// validates each record of a document. Returns true if any record failed checks.
+func (v validator) validateDocument(name string, r io.Reader, lines bool, rep reporter) (bool, error) {
+	failed := false
+	validate := func(record int, doc interface{}) error {
+		res := result{Source: name, Name: name, Record: record, Checks: map[structcheck.Field][]string{}, Absent: map[structcheck.Field]bool{}}
+		if record != 0 {
+			res.Source = fmt.Sprintf("%v:%d", name, record)
+		}
+		if doc != nil {
+			err := structcheck.CustomValidate(doc, v.finder, structcheck.WithUntyped())
+			checksFailed, ok := err.(structcheck.ErrorChecksFailed)
+			if err != nil && !ok {
+				return fmt.Errorf("%v: %v", res.Source, err)
+			}
+			for field, checks := range checksFailed.Field2Checks {
+				res.Checks[field] = checks
+				res.Fields = append(res.Fields, field)
+			}
+			sort.Sort(structcheck.ByFieldOrder(res.Fields))
+		}
+		// absent keys aren't traversed, so they're reported after the fields that failed checks
+		absent := []string{}
+		for _, segments := range v.required {
+			absent = findAbsent(doc, "", segments, absent)
+		}
+		for _, path := range absent {
+			field := structcheck.Field{Name: path, Path: path}
+			if !res.Absent[field] {
+				res.Absent[field] = true
+				res.Checks[field] = []string{"Required"}
+				res.Fields = append(res.Fields, field)
+			}
+		}
+		if len(res.Fields) != 0 {
+			failed = true
+		}
+		return rep.Report(res)
+	}
+
+	if !lines {
+		var doc interface{}
+		if err := json.NewDecoder(r).Decode(&doc); err != nil {
+			return false, fmt.Errorf("%v: %v", name, err)
+		}
+		err := validate(0, doc)
+		return failed, err
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var doc interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			return failed, fmt.Errorf("%v:%d: %v", name, lineNum, err)
+		}
+		if err := validate(lineNum, doc); err != nil {
+			return failed, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return failed, fmt.Errorf("%v: %v", name, err)
+	}
+	return failed, nil
+}
+
+// prints compiler-style lines, e.g. config.ndjson:3: items[1].id: Positive (0)
+type humanReporter struct {
+	w io.Writer
+}
+
+func (h *humanReporter) Report(r result) error {
+	for _, field := range r.Fields {
+		if _, err := fmt.Fprintf(h.w, "%v: %v: %v (%v)\n", r.Source, field.Path, strings.Join(r.Checks[field], ", "), r.value(field)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *humanReporter) EndDocument() error {
+	return nil
+}
+
+func (h *humanReporter) Flush() error {
+	return nil
+}
+
+// prints one JSON object per failing field
+type jsonReporter struct {
+	enc *json.Encoder
+}
+
+type jsonFailure struct {
+	Document string   `json:"document"`
+	Record   int      `json:"record,omitempty"`
+	Path     string   `json:"path"`
+	Checks   []string `json:"checks"`
+	Value    string   `json:"value,omitempty"`
+	Absent   bool     `json:"absent,omitempty"`
+}
+
+func (j *jsonReporter) Report(r result) error {
+	for _, field := range r.Fields {
+		err := j.enc.Encode(jsonFailure{
+			Document: r.Name,
+			Record:   r.Record,
+			Path:     field.Path,
+			Checks:   r.Checks[field],
+			Value:    field.Value,
+			Absent:   r.Absent[field],
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *jsonReporter) EndDocument() error {
+	return nil
+}
+
+func (j *jsonReporter) Flush() error {
+	return nil
+}
+
+// writes one test suite per document as the document finishes, with one test case per record. Flush closes the root element.
+type junitReporter struct {
+	w       io.Writer
+	enc     *xml.Encoder // nil until the root element is opened
+	current *junitSuite  // the suite of the document being validated, if it has results
+}
+
+var junitRoot = xml.StartElement{Name: xml.Name{Local: "testsuites"}}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name    string        `xml:"name,attr"`
+	Failure *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (j *junitReporter) Report(r result) error {
+	if j.current == nil {
+		j.current = &junitSuite{Name: r.Name}
+	}
+	suite := j.current
+	suite.Tests++
+	c := junitCase{Name: r.Source}
+	if len(r.Fields) != 0 {
+		suite.Failures++
+		lines := make([]string, len(r.Fields))
+		for i, field := range r.Fields {
+			lines[i] = fmt.Sprintf("%v: %v (%v)", field.Path, strings.Join(r.Checks[field], ", "), r.value(field))
+		}
+		c.Failure = &junitFailure{
+			Message: fmt.Sprintf("%d field(s) failed checks", len(r.Fields)),
+			Text:    strings.Join(lines, "\n"),
+		}
+	}
+	suite.Cases = append(suite.Cases, c)
+	return nil
+}
+
+func (j *junitReporter) EndDocument() error {
+	if j.current == nil {
+		return nil
+	}
+	if err := j.open(); err != nil {
+		return err
+	}
+	suite := j.current
+	j.current = nil
+	return j.enc.Encode(suite)
+}
+
+func (j *junitReporter) Flush() error {
+	if err := j.EndDocument(); err != nil {
+		return err
+	}
+	if err := j.open(); err != nil {
+		return err
+	}
+	if err := j.enc.EncodeToken(junitRoot.End()); err != nil {
+		return err
+	}
+	if err := j.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n")
+	return err
+}
+
+// writes the XML header and opens the root element, once
+func (j *junitReporter) open() error {
+	if j.enc != nil {
+		return nil
+	}
+	if _, err := io.WriteString(j.w, xml.Header); err != nil {
+		return err
+	}
+	j.enc = xml.NewEncoder(j.w)
+	j.enc.Indent("", "  ")
+	return j.enc.EncodeToken(junitRoot)
+}
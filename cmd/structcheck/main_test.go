@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConstraints = `
+# comment
+name: NotEmpty
+items[*].id: Positive
+`
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func runTest(t *testing.T, args ...string) (int, string, string) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	status := run(args, strings.NewReader(`{"name": ""}`), stdout, stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestRun_human(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"c.txt":      testConstraints,
+		"good.json":  `{"name": "a", "items": [{"id": 1}]}`,
+		"bad.ndjson": "{\"name\": \"a\"}\n\n{\"name\": \"\", \"items\": [{\"id\": 1}, {\"id\": 0}]}\n",
+	})
+	status, stdout, _ := runTest(t, "-constraints", filepath.Join(dir, "c.txt"), filepath.Join(dir, "good.json"), filepath.Join(dir, "bad.ndjson"), "-")
+	require.Equal(t, 1, status)
+	bad := filepath.Join(dir, "bad.ndjson")
+	require.Equal(t, bad+":3: items[1].id: Positive (0)\n"+
+		bad+":3: name: NotEmpty (\"\")\n"+
+		"-: name: NotEmpty (\"\")\n", stdout)
+}
+
+func TestRun_good(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"c.txt":     testConstraints,
+		"good.json": `{"name": "a", "items": [{"id": 1}]}`,
+	})
+	status, stdout, _ := runTest(t, "-constraints", filepath.Join(dir, "c.txt"), filepath.Join(dir, "good.json"))
+	require.Equal(t, 0, status)
+	require.Empty(t, stdout)
+}
+
+func TestRun_json(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{"c.txt": testConstraints})
+	status, stdout, _ := runTest(t, "-constraints", filepath.Join(dir, "c.txt"), "-output", "json", "-")
+	require.Equal(t, 1, status)
+	require.Equal(t, `{"document":"-","path":"name","checks":["NotEmpty"],"value":"\"\""}`+"\n", stdout)
+}
+
+func TestRun_junit(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"c.txt":      testConstraints,
+		"doc.ndjson": "{\"name\": \"a\"}\n{\"name\": \"\"}\n",
+	})
+	status, stdout, _ := runTest(t, "-constraints", filepath.Join(dir, "c.txt"), "-output", "junit", filepath.Join(dir, "doc.ndjson"))
+	require.Equal(t, 1, status)
+	require.Contains(t, stdout, `<testsuite name="`+filepath.Join(dir, "doc.ndjson")+`" tests="2" failures="1">`)
+	require.Contains(t, stdout, `<failure message="1 field(s) failed checks">name: NotEmpty (&#34;&#34;)</failure>`)
+}
+
+func TestJunitReporter_streams(t *testing.T) {
+	buf := new(bytes.Buffer)
+	rep := &junitReporter{w: buf}
+	require.NoError(t, rep.Report(result{Name: "a.json", Source: "a.json"}))
+	require.Empty(t, buf.String())
+	require.NoError(t, rep.EndDocument())
+	require.Contains(t, buf.String(), `<testsuite name="a.json" tests="1" failures="0">`)
+	require.True(t, strings.HasSuffix(buf.String(), "</testsuite>"))
+	require.NoError(t, rep.Flush())
+	require.True(t, strings.HasSuffix(buf.String(), "</testsuite>\n</testsuites>\n"))
+}
+
+func TestRun_required(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"c.txt":        "name: Required, NotEmpty\nitems[*].id: Required\nowner.email: Required\n",
+		"good.json":    `{"name": "a", "items": [{"id": 0}], "owner": {"email": null}}`,
+		"bad.ndjson":   "{\"items\": [{\"id\": 1}, {}]}\nnull\n",
+		"double.txt":   "**.id: Required\n",
+		"noitems.json": `{"name": "a", "owner": {"email": "x"}}`,
+	})
+	c := filepath.Join(dir, "c.txt")
+	status, stdout, _ := runTest(t, "-constraints", c, filepath.Join(dir, "good.json"))
+	require.Empty(t, stdout)
+	require.Equal(t, 0, status)
+	// the literal keys of a pattern are required too, while wildcards match what's there
+	noItems := filepath.Join(dir, "noitems.json")
+	status, stdout, _ = runTest(t, "-constraints", c, noItems)
+	require.Equal(t, 1, status)
+	require.Equal(t, noItems+": items: Required (absent)\n", stdout)
+	bad := filepath.Join(dir, "bad.ndjson")
+	status, stdout, _ = runTest(t, "-constraints", c, bad)
+	require.Equal(t, 1, status)
+	require.Equal(t, bad+":1: items[1].id: Required (absent)\n"+
+		bad+":1: name: Required (absent)\n"+
+		bad+":1: owner: Required (absent)\n"+
+		bad+":2: items: Required (absent)\n"+
+		bad+":2: name: Required (absent)\n"+
+		bad+":2: owner: Required (absent)\n", stdout)
+	status, stdout, _ = runTest(t, "-constraints", c, "-output", "json", "-")
+	require.Equal(t, 1, status)
+	require.Equal(t, `{"document":"-","path":"name","checks":["NotEmpty"],"value":"\"\""}`+"\n"+
+		`{"document":"-","path":"items","checks":["Required"],"absent":true}`+"\n"+
+		`{"document":"-","path":"owner","checks":["Required"],"absent":true}`+"\n", stdout)
+	status, _, _ = runTest(t, "-constraints", filepath.Join(dir, "double.txt"), "-")
+	require.Equal(t, 2, status)
+}
+
+func TestRun_errors(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"c.txt":     "name NotEmpty",
+		"ok.txt":    testConstraints,
+		"bad.json":  `{"name": `,
+		"weird.txt": "name: NotAThing",
+	})
+	status, _, _ := runTest(t, "-constraints", filepath.Join(dir, "c.txt"), "-")
+	require.Equal(t, 2, status)
+	status, _, _ = runTest(t, "-constraints", filepath.Join(dir, "weird.txt"), "-")
+	require.Equal(t, 2, status)
+	status, _, stderr := runTest(t, "-constraints", filepath.Join(dir, "ok.txt"), filepath.Join(dir, "bad.json"))
+	require.Equal(t, 2, status)
+	require.Contains(t, stderr, "bad.json")
+	status, _, _ = runTest(t, "-constraints", filepath.Join(dir, "ok.txt"), filepath.Join(dir, "missing.json"))
+	require.Equal(t, 2, status)
+	status, _, _ = runTest(t)
+	require.Equal(t, 2, status)
+}
@@ -0,0 +1,311 @@
+package structcheck
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Loads configuration structs from environment variables and command-line flags. Fields are bound with `env:"NAME"` and `flag:"name"` tags (and an optional `usage:"..."` tag for flag help); flags take precedence over environment variables, and unbound or unset fields keep their current values as defaults. Nested structs and pointers to structs are searched for tagged fields; nil pointers to structs with bound fields are allocated.
+//
+// Flags are defined on FlagSet the first time Load binds them. Later calls reuse those flags for the struct they load, so a loader (or FlagSet) can load several times. Flag help leaves out the defaults of fields whose values would be redacted from failures.
+type ConfigLoader struct {
+	LookupEnv func(key string) (string, bool) // nil means os.LookupEnv
+	FlagSet   *flag.FlagSet                   // nil means flags aren't read
+	Args      []string                        // the arguments parsed by FlagSet
+	Options   []Option                        // options for validating the loaded struct
+}
+
+// where a configuration field's value came from
+type ConfigSource struct {
+	Env  string // the field's environment variable, if any
+	Flag string // the field's flag, if any
+	From string // "flag", "env" or "default"
+}
+
+// describes the field's bindings, e.g. -port/$PORT
+func (s ConfigSource) String() string {
+	names := []string{}
+	if s.Flag != "" {
+		names = append(names, "-"+s.Flag)
+	}
+	if s.Env != "" {
+		names = append(names, "$"+s.Env)
+	}
+	return strings.Join(names, "/")
+}
+
+// the sources of a loaded configuration's fields, keyed by field path (e.g. Database.URL)
+type ConfigReport struct {
+	Sources map[string]ConfigSource
+}
+
+// returns the paths of bound fields that were left at their defaults, in sorted order
+func (r *ConfigReport) Defaulted() []string {
+	paths := []string{}
+	for path, source := range r.Sources {
+		if source.From == "default" {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// returns the source of the field at path, using its closest bound ancestor for unbound fields and elements
+func (r *ConfigReport) sourceFor(path string) (ConfigSource, bool) {
+	segments, _ := splitPath(path)
+	for i := len(segments); i > 0; i-- {
+		if source, ok := r.Sources[joinName(segments[:i])]; ok {
+			return source, true
+		}
+	}
+	return ConfigSource{}, false
+}
+
+// Fills struct v (a non-nil pointer) from the environment and flags, then validates it. Validation failures are returned as an ErrorConfigInvalid naming the variables and flags that supplied each field.
+func (l ConfigLoader) Load(v interface{}) (*ConfigReport, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, fmt.Errorf("Load requires a non-nil pointer. Received: %T", v)
+	}
+	rv, err := drillDown(rv)
+	if err != nil {
+		return nil, err
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, ErrorInvalidKind{Type: rv.Type()}
+	}
+	lookupEnv := l.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	report := &ConfigReport{Sources: make(map[string]ConfigSource)}
+	bindings := []configBinding{}
+	bindConfig(rv, structScope{t: rv.Type()}, nil, false, &bindings)
+	o := newOptions(l.Options)
+	flags := make(map[string]*fieldFlag)
+	for _, b := range bindings {
+		b.sensitive = b.sensitive || o.belowSensitiveType(b.types) || o.containsSensitive(b.value.Type(), map[reflect.Type]bool{})
+		source := ConfigSource{Env: b.env, Flag: b.flag, From: "default"}
+		if b.env != "" {
+			if value, ok := lookupEnv(b.env); ok {
+				if err := setFromStrings(b.value, splitEnvValue(b.value, value)); err != nil {
+					return nil, fmt.Errorf("Invalid value for $%v: %v", b.env, err)
+				}
+				source.From = "env"
+			}
+		}
+		if b.flag != "" && l.FlagSet != nil {
+			if flags[b.flag] != nil {
+				return nil, fmt.Errorf("Flag -%v is bound to more than one field", b.flag)
+			}
+			if flags[b.flag], err = defineFieldFlag(l.FlagSet, b); err != nil {
+				return nil, err
+			}
+		}
+		report.Sources[b.path] = source
+	}
+	if l.FlagSet != nil {
+		if err := l.FlagSet.Parse(l.Args); err != nil {
+			return nil, err
+		}
+		for _, b := range bindings {
+			if f := flags[b.flag]; f != nil && f.set {
+				source := report.Sources[b.path]
+				source.From = "flag"
+				report.Sources[b.path] = source
+			}
+		}
+	}
+	err = Validate(v, l.Options...)
+	checksFailed, ok := err.(ErrorChecksFailed)
+	if !ok {
+		return report, err
+	}
+	fields := make([]Field, 0, len(checksFailed.Field2Checks))
+	for field := range checksFailed.Field2Checks {
+		fields = append(fields, field)
+	}
+	sort.Sort(ByFieldOrder(fields))
+	failures := make([]ConfigFailure, len(fields))
+	for i, field := range fields {
+		source, _ := report.sourceFor(field.Path)
+		failures[i] = ConfigFailure{Field: field, Checks: checksFailed.Field2Checks[field], Source: source}
+	}
+	return report, ErrorConfigInvalid{Failures: failures}
+}
+
+// a field bound to an environment variable or flag
+type configBinding struct {
+	path      string
+	value     reflect.Value
+	types     []reflect.Type // the types of the field and the structs above it
+	env       string
+	flag      string
+	usage     string
+	sensitive bool // the field's value is left out of flag help (see DirectiveSensitive)
+}
+
+// parents are the types of the structs above v, whose pointers aren't followed again. sensitive is set if v or a struct above it is tagged sensitive.
+func bindConfig(v reflect.Value, scope structScope, parents []reflect.Type, sensitive bool, bindings *[]configBinding) {
+	t := v.Type()
+	parents = append(parents[:len(parents):len(parents)], t)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fieldPath, _, fieldScope := scope.field(i)
+		fieldSensitive := sensitive || hasDirective(f.Tag, DirectiveSensitive)
+		env, flagName := f.Tag.Get("env"), f.Tag.Get("flag")
+		if env != "" || flagName != "" {
+			*bindings = append(*bindings, configBinding{
+				path:      joinName(fieldPath),
+				value:     v.Field(i),
+				types:     append(parents[:len(parents):len(parents)], f.Type),
+				env:       env,
+				flag:      flagName,
+				usage:     f.Tag.Get("usage"),
+				sensitive: fieldSensitive,
+			})
+		} else if f.Type.Kind() == reflect.Struct {
+			bindConfig(v.Field(i), fieldScope, parents, fieldSensitive, bindings)
+		} else if isConfigStructPointer(f.Type, parents) {
+			if v.Field(i).IsNil() {
+				v.Field(i).Set(reflect.New(f.Type.Elem()))
+			}
+			bindConfig(v.Field(i).Elem(), fieldScope, parents, fieldSensitive, bindings)
+		}
+	}
+}
+
+// true if t is a pointer to a struct with bound fields that isn't one of parents
+func isConfigStructPointer(t reflect.Type, parents []reflect.Type) bool {
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	for _, parent := range parents {
+		if t.Elem() == parent {
+			return false
+		}
+	}
+	return hasConfigBindings(t.Elem(), map[reflect.Type]bool{})
+}
+
+// true if struct type t has fields bound to environment variables or flags, directly or through nested structs
+func hasConfigBindings(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if f.Tag.Get("env") != "" || f.Tag.Get("flag") != "" {
+			return true
+		}
+		if ft := derefType(f.Type); ft.Kind() == reflect.Struct && hasConfigBindings(ft, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// defines b's flag on fs, or points the flag an earlier Load defined at b's field
+func defineFieldFlag(fs *flag.FlagSet, b configBinding) (*fieldFlag, error) {
+	existing := fs.Lookup(b.flag)
+	if existing == nil {
+		f := &fieldFlag{value: b.value, sensitive: b.sensitive}
+		fs.Var(f, b.flag, b.usage)
+		return f, nil
+	}
+	f, ok := existing.Value.(*fieldFlag)
+	if !ok || f.value.Type() != b.value.Type() {
+		return nil, fmt.Errorf("Flag -%v is already defined", b.flag)
+	}
+	f.value = b.value
+	f.set = false
+	f.sensitive = b.sensitive
+	return f, nil
+}
+
+// environment variables bound to slices hold comma-separated values
+func splitEnvValue(v reflect.Value, value string) []string {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		return strings.Split(value, ",")
+	}
+	return []string{value}
+}
+
+// a flag.Value that sets a struct field. Slice fields collect repeated flags.
+type fieldFlag struct {
+	value     reflect.Value
+	set       bool // the flag was set since it was bound to value
+	sensitive bool // the default isn't shown in help
+}
+
+func (f *fieldFlag) String() string {
+	if f == nil || !f.value.IsValid() || f.sensitive {
+		return ""
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+func (f *fieldFlag) Set(s string) error {
+	if f.value.Kind() == reflect.Slice && f.value.Type().Elem().Kind() != reflect.Uint8 {
+		elem := reflect.New(f.value.Type().Elem()).Elem()
+		if err := setFromString(elem, s); err != nil {
+			return err
+		}
+		if !f.set {
+			f.value.Set(reflect.MakeSlice(f.value.Type(), 0, 1))
+		}
+		f.value.Set(reflect.Append(f.value, elem))
+		f.set = true
+		return nil
+	}
+	if err := setFromString(f.value, s); err != nil {
+		return err
+	}
+	f.set = true
+	return nil
+}
+
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.value.IsValid() && f.value.Kind() == reflect.Bool
+}
+
+// a configuration field that failed checks
+type ConfigFailure struct {
+	Field
+	Checks []string
+	Source ConfigSource // the zero value if the field isn't bound
+}
+
+// returned by ConfigLoader.Load when the loaded configuration fails checks
+type ErrorConfigInvalid struct {
+	Failures []ConfigFailure // in field order
+}
+
+func (e ErrorConfigInvalid) Error() string {
+	lines := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		name := f.Source.String()
+		if name == "" {
+			name = f.Path
+		}
+		lines[i] = fmt.Sprintf("\n    %v: %v", name, strings.Join(f.Checks, ", "))
+		if f.Source.From == "default" && f.Value != "" {
+			lines[i] += fmt.Sprintf(" (not set, default %v)", f.Value)
+		} else if f.Source.From == "default" {
+			lines[i] += " (not set)"
+		}
+	}
+	return fmt.Sprintf("Invalid configuration: %v", strings.Join(lines, ""))
+}
@@ -0,0 +1,144 @@
+package structcheck
+
+import (
+	"flag"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type configTestConfig struct {
+	Port     int           `env:"PORT" flag:"port" usage:"port to listen on" checks:"Positive"`
+	Timeout  time.Duration `env:"TIMEOUT" checks:"Positive"`
+	Verbose  bool          `flag:"v"`
+	Hosts    []string      `env:"HOSTS" flag:"host" checks:"NotEmpty"`
+	Database struct {
+		URL string `env:"DB_URL" checks:"NotEmpty"`
+	}
+	Unbound string
+}
+
+func testEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestConfigLoader_good(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c := configTestConfig{Timeout: time.Second}
+	report, err := ConfigLoader{
+		LookupEnv: testEnv(map[string]string{"PORT": "80", "HOSTS": "a,b", "DB_URL": "postgres://"}),
+		FlagSet:   fs,
+		Args:      []string{"-port", "8080", "-v", "-host", "c"},
+	}.Load(&c)
+	require.NoError(t, err)
+	require.Equal(t, 8080, c.Port)
+	require.True(t, c.Verbose)
+	require.Equal(t, []string{"c"}, c.Hosts)
+	require.Equal(t, "postgres://", c.Database.URL)
+	require.Equal(t, ConfigSource{Env: "PORT", Flag: "port", From: "flag"}, report.Sources["Port"])
+	require.Equal(t, ConfigSource{Env: "DB_URL", From: "env"}, report.Sources["Database.URL"])
+	require.Equal(t, []string{"Timeout"}, report.Defaulted())
+}
+
+func TestConfigLoader_bad(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	_, err := ConfigLoader{
+		LookupEnv: testEnv(map[string]string{"PORT": "-1"}),
+		FlagSet:   fs,
+	}.Load(&configTestConfig{})
+	require.IsType(t, ErrorConfigInvalid{}, err)
+	require.Equal(t, "Invalid configuration: \n"+
+		"    -port/$PORT: Positive\n"+
+		"    $TIMEOUT: Positive (not set, default time.Duration(0s))\n"+
+		"    -host/$HOSTS: NotEmpty (not set, default []string(nil))\n"+
+		"    $DB_URL: NotEmpty (not set, default \"\")", err.Error())
+}
+
+type configTestPointers struct {
+	Cache *struct {
+		Size int `env:"CACHE_SIZE" flag:"cache-size" checks:"Positive"`
+	}
+	Next  *configTestPointers
+	Other *struct {
+		Unbound int
+	}
+}
+
+func TestConfigLoader_pointers(t *testing.T) {
+	var c configTestPointers
+	report, err := ConfigLoader{LookupEnv: testEnv(map[string]string{"CACHE_SIZE": "64"})}.Load(&c)
+	require.NoError(t, err)
+	require.Equal(t, 64, c.Cache.Size)
+	require.Nil(t, c.Next)
+	require.Nil(t, c.Other)
+	require.Equal(t, ConfigSource{Env: "CACHE_SIZE", Flag: "cache-size", From: "env"}, report.Sources["Cache.Size"])
+}
+
+func TestConfigLoader_twice(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := ConfigLoader{
+		LookupEnv: testEnv(map[string]string{"TIMEOUT": "1s", "HOSTS": "a", "DB_URL": "postgres://"}),
+		FlagSet:   fs,
+		Args:      []string{"-port", "8080"},
+	}
+	var first, second configTestConfig
+	_, err := loader.Load(&first)
+	require.NoError(t, err)
+	loader.Args = []string{"-port", "9090", "-host", "b"}
+	report, err := loader.Load(&second)
+	require.NoError(t, err)
+	require.Equal(t, 8080, first.Port)
+	require.Equal(t, []string{"a"}, first.Hosts)
+	require.Equal(t, 9090, second.Port)
+	require.Equal(t, []string{"b"}, second.Hosts)
+	require.Equal(t, "flag", report.Sources["Hosts"].From)
+	// flags set by an earlier Load don't count as set
+	loader.Args = nil
+	report, err = ConfigLoader{LookupEnv: loader.LookupEnv, FlagSet: fs}.Load(&configTestConfig{Port: 1})
+	require.NoError(t, err)
+	require.Equal(t, "default", report.Sources["Port"].From)
+}
+
+func TestConfigLoader_conflictingFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("port", 0, "")
+	_, err := ConfigLoader{FlagSet: fs}.Load(&configTestConfig{})
+	require.EqualError(t, err, "Flag -port is already defined")
+}
+
+func TestConfigLoader_badValue(t *testing.T) {
+	_, err := ConfigLoader{LookupEnv: testEnv(map[string]string{"TIMEOUT": "soon"})}.Load(&configTestConfig{})
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "Invalid configuration")
+}
+
+func TestConfigLoader_sensitiveDefaults(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	help := new(strings.Builder)
+	fs.SetOutput(help)
+	cfg := struct {
+		Password string `flag:"password" checks:"sensitive"`
+		Secrets  struct {
+			Token string `flag:"token"`
+		} `checks:"sensitive"`
+		Key  redactToken `flag:"key"`
+		Card redactCard  `flag:"card"`
+		Name string      `flag:"name"`
+	}{Password: "hunter2", Key: redactToken{"k3ysecret"}, Card: "4111111111111111", Name: "bob"}
+	cfg.Secrets.Token = "t0ken"
+	loader := ConfigLoader{FlagSet: fs, Args: []string{}, Options: []Option{WithSensitiveTypes(reflect.TypeOf(redactToken{}))}}
+	_, err := loader.Load(&cfg)
+	require.NoError(t, err)
+	fs.PrintDefaults()
+	require.Contains(t, help.String(), "(default bob)")
+	for _, secret := range []string{"hunter2", "t0ken", "k3y", "4111"} {
+		require.NotContains(t, help.String(), secret)
+	}
+}
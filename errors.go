@@ -2,6 +2,7 @@ package structcheck
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"sort"
@@ -25,7 +26,17 @@ type ErrorIllegalCheck struct {
 }
 
 func (e ErrorIllegalCheck) Error() string {
-	return fmt.Sprintf("Encountered illegal check on %v: %v", strings.Join(e.value.Name, "."), e.Reason)
+	return fmt.Sprintf("Encountered illegal check on %v: %v", joinName(e.value.Name), e.Reason)
+}
+
+// returned when a field mask names fields that don't exist in the validated type
+type ErrorUnknownFields struct {
+	Type   reflect.Type
+	Fields []string
+}
+
+func (e ErrorUnknownFields) Error() string {
+	return fmt.Sprintf("Field(s) %v do not exist in %v", e.Fields, e.Type)
 }
 
 // returned when a top level nil is received
@@ -38,6 +49,34 @@ func (e ErrorNilValue) Error() string {
 // returned when checks fail on fields
 type ErrorChecksFailed struct {
 	Field2Checks map[Field][]string
+	Groups       []string           // the check groups that were active during validation
+	Aliases      map[Field][]string // other names of failing fields that were reached through more than one pointer
+	Messages     map[Field][]string // a user-facing message for each failed check, in the order of Field2Checks (see DefaultMessages and MessageTag)
+	Skipped      map[Field][]string // checks that couldn't run because they need Interface() on values read from unexported fields
+}
+
+// returns a "Path: message" line for each failed check, in field order
+func (e ErrorChecksFailed) UserMessages() []string {
+	sortedFields := make([]Field, 0, len(e.Field2Checks))
+	for field := range e.Field2Checks {
+		sortedFields = append(sortedFields, field)
+	}
+	sort.Sort(ByFieldOrder(sortedFields))
+	lines := []string{}
+	for _, field := range sortedFields {
+		for i := range e.Field2Checks[field] {
+			lines = append(lines, fmt.Sprintf("%v: %v", field.Path, e.message(field, i)))
+		}
+	}
+	return lines
+}
+
+// returns the message for field's i'th failed check, falling back to the check's name
+func (e ErrorChecksFailed) message(field Field, i int) string {
+	if i < len(e.Messages[field]) {
+		return e.Messages[field][i]
+	}
+	return e.Field2Checks[field][i]
 }
 
 func (e ErrorChecksFailed) Error() string {
@@ -54,8 +93,116 @@ func (e ErrorChecksFailed) Error() string {
 		for _, check := range checks {
 			fails = append(fails, check)
 		}
-		failWriter.Write([]byte(fmt.Sprintf("\n\t%v:\t%v:\t%v", field.Name, strings.Join(fails, ", "), field.Value)))
+		line := fmt.Sprintf("\n\t%v:\t%v", field.Name, strings.Join(fails, ", "))
+		if field.Value != "" {
+			line += fmt.Sprintf(":\t%v", field.Value)
+		}
+		if aliases := e.Aliases[field]; len(aliases) != 0 {
+			line += fmt.Sprintf(" (also %v)", strings.Join(aliases, ", "))
+		}
+		failWriter.Write([]byte(line))
 	}
 	failWriter.Flush()
+	if len(e.Groups) != 0 {
+		return fmt.Sprintf("The following field(s) failed checks (groups: %v): %v", strings.Join(e.Groups, ", "), buf.String())
+	}
 	return fmt.Sprintf("The following field(s) failed checks: %v", buf.String())
 }
+
+// returned when validation stops because it exceeded one of its Limits
+type ErrorLimitExceeded struct {
+	Limit        string             // the name of the Limits field that was exceeded (e.g. MaxDepth)
+	Max          int                // the value of that limit
+	Path         string             // the path of the field where the limit was hit, relative to the root
+	Field2Checks map[Field][]string // the failures recorded before validation stopped
+}
+
+func newErrorLimitExceeded(limit string, max int, v metaValue) ErrorLimitExceeded {
+	return ErrorLimitExceeded{Limit: limit, Max: max, Path: joinName(v.Name[1:])}
+}
+
+func (e ErrorLimitExceeded) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "the root"
+	}
+	return fmt.Sprintf("Validation stopped at %v: %v of %d exceeded", path, e.Limit, e.Max)
+}
+
+// returned by DecodeAndValidateJSON when a document fails checks or contains unknown fields
+type ErrorJSONInvalid struct {
+	Source        string
+	Failures      []JSONFailure      // in field order
+	UnknownFields []JSONUnknownField // in document order
+}
+
+// a field of a JSON document that failed checks
+type JSONFailure struct {
+	Field
+	Checks   []string
+	Present  bool     // false if the field was absent from the document
+	Position Position // the position of the field's value, if present
+}
+
+func (e ErrorJSONInvalid) Error() string {
+	lines := make([]string, 0, len(e.Failures)+len(e.UnknownFields))
+	for _, f := range e.Failures {
+		if f.Present {
+			lines = append(lines, fmt.Sprintf("%v:%v: %v: %v", e.Source, f.Position, f.Path, strings.Join(f.Checks, ", ")))
+		} else {
+			lines = append(lines, fmt.Sprintf("%v: %v: %v (absent)", e.Source, f.Path, strings.Join(f.Checks, ", ")))
+		}
+	}
+	for _, f := range e.UnknownFields {
+		lines = append(lines, fmt.Sprintf("%v:%v: %v: unknown field", e.Source, f.Position, f.Path))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// returned by DecodeAndValidateJSON when a document can't be decoded
+type ErrorJSONDecode struct {
+	Source   string
+	Position *Position // nil if the error has no location
+	Err      error
+}
+
+func newErrorJSONDecode(source string, data []byte, err error) ErrorJSONDecode {
+	e := ErrorJSONDecode{Source: source, Err: err}
+	offset := int64(-1)
+	switch err := err.(type) {
+	case *json.SyntaxError:
+		offset = err.Offset
+	case *json.UnmarshalTypeError:
+		offset = err.Offset
+	}
+	if offset >= 0 {
+		position := newPresence(data).position(offset)
+		e.Position = &position
+	}
+	return e
+}
+
+func (e ErrorJSONDecode) Error() string {
+	if e.Position != nil {
+		return fmt.Sprintf("%v:%v: %v", e.Source, *e.Position, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Source, e.Err)
+}
+
+// returned by DecodeForm when inputs can't be parsed into their fields
+type ErrorFormDecode struct {
+	Inputs map[string]error
+}
+
+func (e ErrorFormDecode) Error() string {
+	inputs := make([]string, 0, len(e.Inputs))
+	for input := range e.Inputs {
+		inputs = append(inputs, input)
+	}
+	sort.Strings(inputs)
+	msgs := make([]string, len(inputs))
+	for i, input := range inputs {
+		msgs[i] = fmt.Sprintf("%v: %v", input, e.Inputs[input])
+	}
+	return fmt.Sprintf("Invalid form input(s): %v", strings.Join(msgs, "; "))
+}
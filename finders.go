@@ -0,0 +1,117 @@
+package structcheck
+
+import (
+	"reflect"
+)
+
+// Builds a CheckFinder that runs the checks found by all of finders. Checks are de-duplicated by name; the first finder to name a check wins.
+func BuildUnionCheckFinder(finders ...CheckFinder) CheckFinder {
+	return func(v metaValue) ([]Check, []string, error) {
+		checks := []Check{}
+		checkNames := []string{}
+		for _, finder := range finders {
+			c, n, err := finder(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i, name := range n {
+				if !containsString(checkNames, name) {
+					checks = append(checks, c[i])
+					checkNames = append(checkNames, name)
+				}
+			}
+		}
+		return checks, checkNames, nil
+	}
+}
+
+// Builds a CheckFinder that uses override's checks instead of base's on fields matching any of patterns (see BuildStringyCheckFinder for the pattern syntax). A nil override suppresses all checks on those fields.
+func BuildOverrideCheckFinder(base CheckFinder, patterns []string, override CheckFinder) (CheckFinder, error) {
+	compiled, err := parsePathPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return func(v metaValue) ([]Check, []string, error) {
+		if v.lookup != nil && override != nil {
+			return BuildUnionCheckFinder(override, base)(v)
+		}
+		if v.lookup != nil || !matchesAny(compiled, v) {
+			return base(v)
+		}
+		if override == nil {
+			return []Check{}, []string{}, nil
+		}
+		return override(v)
+	}, nil
+}
+
+// Builds a CheckFinder that drops the named checks found by base on fields matching any of patterns
+func BuildSuppressCheckFinder(base CheckFinder, patterns []string, checkNames ...string) (CheckFinder, error) {
+	compiled, err := parsePathPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return func(v metaValue) ([]Check, []string, error) {
+		checks, names, err := base(v)
+		if err != nil || v.lookup != nil || !matchesAny(compiled, v) {
+			return checks, names, err
+		}
+		keptChecks := []Check{}
+		keptNames := []string{}
+		for i, name := range names {
+			if !containsString(checkNames, name) {
+				keptChecks = append(keptChecks, checks[i])
+				keptNames = append(keptNames, name)
+			}
+		}
+		return keptChecks, keptNames, nil
+	}, nil
+}
+
+// Builds a CheckFinder that only applies finder to fields matching pattern and everything below them
+func BuildSubtreeCheckFinder(finder CheckFinder, pattern string) (CheckFinder, error) {
+	compiled, err := parsePathPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	compiled = compiled.subtree()
+	return func(v metaValue) ([]Check, []string, error) {
+		if v.lookup == nil && !compiled.Match(v.Name[1:], v.Types[1:]) {
+			return []Check{}, []string{}, nil
+		}
+		return finder(v)
+	}, nil
+}
+
+// Builds a CheckFinder that only applies finder to values of the given kinds
+func BuildKindCheckFinder(finder CheckFinder, kinds ...reflect.Kind) CheckFinder {
+	return func(v metaValue) ([]Check, []string, error) {
+		for _, kind := range kinds {
+			if v.lookup != nil || v.Kind() == kind {
+				return finder(v)
+			}
+		}
+		return []Check{}, []string{}, nil
+	}
+}
+
+func parsePathPatterns(patterns []string) ([]pathPattern, error) {
+	compiled := make([]pathPattern, len(patterns))
+	for i, pattern := range patterns {
+		p, err := parsePathPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled[i] = p
+	}
+	return compiled, nil
+}
+
+func matchesAny(patterns []pathPattern, v metaValue) bool {
+	for _, pattern := range patterns {
+		if pattern.Match(v.Name[1:], v.Types[1:]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package structcheck
+
+import (
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"testing"
+)
+
+type finderTestStruct struct {
+	ID       *int `checks:"NotNil"`
+	Name     string
+	Internal struct {
+		Token *string
+		Notes []string
+	}
+}
+
+func TestBuildUnionCheckFinder(t *testing.T) {
+	fixed, err := BuildFixedCheckFinder([]string{"NotNil", "NotEmpty"}, DefaultChecks)
+	require.NoError(t, err)
+	finder := BuildUnionCheckFinder(BuildTagCheckFinder(DefaultChecks), fixed)
+	err = CustomValidate(finderTestStruct{}, finder)
+	require.Equal(t, map[string][]string{
+		"finderTestStruct.ID":             {"NotNil"},
+		"finderTestStruct.Name":           {"NotEmpty"},
+		"finderTestStruct.Internal.Token": {"NotNil"},
+		"finderTestStruct.Internal.Notes": {"NotNil", "NotEmpty"},
+	}, failuresByName(t, err))
+}
+
+func TestBuildOverrideCheckFinder(t *testing.T) {
+	fixed, err := BuildFixedCheckFinder([]string{"NotNil"}, DefaultChecks)
+	require.NoError(t, err)
+	notEmpty, err := BuildFixedCheckFinder([]string{"NotEmpty"}, DefaultChecks)
+	require.NoError(t, err)
+	finder, err := BuildOverrideCheckFinder(fixed, []string{"Internal.Notes"}, notEmpty)
+	require.NoError(t, err)
+	finder, err = BuildOverrideCheckFinder(finder, []string{"Internal.Token"}, nil)
+	require.NoError(t, err)
+	err = CustomValidate(finderTestStruct{}, finder)
+	require.Equal(t, map[string][]string{
+		"finderTestStruct.ID":             {"NotNil"},
+		"finderTestStruct.Internal.Notes": {"NotEmpty"},
+	}, failuresByName(t, err))
+}
+
+func TestBuildSuppressCheckFinder(t *testing.T) {
+	fixed, err := BuildFixedCheckFinder([]string{"NotNil", "NotEmpty"}, DefaultChecks)
+	require.NoError(t, err)
+	finder, err := BuildSuppressCheckFinder(fixed, []string{"**"}, "NotNil")
+	require.NoError(t, err)
+	err = CustomValidate(finderTestStruct{}, finder)
+	require.Equal(t, map[string][]string{
+		"finderTestStruct.Name":           {"NotEmpty"},
+		"finderTestStruct.Internal.Notes": {"NotEmpty"},
+	}, failuresByName(t, err))
+}
+
+func TestBuildSubtreeAndKindCheckFinder(t *testing.T) {
+	fixed, err := BuildFixedCheckFinder([]string{"NotNil"}, DefaultChecks)
+	require.NoError(t, err)
+	finder, err := BuildSubtreeCheckFinder(BuildKindCheckFinder(fixed, reflect.Slice), "Internal")
+	require.NoError(t, err)
+	err = CustomValidate(finderTestStruct{}, finder)
+	require.Equal(t, map[string][]string{
+		"finderTestStruct.Internal.Notes": {"NotNil"},
+	}, failuresByName(t, err))
+}
+
+func failuresByName(t *testing.T, err error) map[string][]string {
+	require.IsType(t, ErrorChecksFailed{}, err)
+	field2checks := err.(ErrorChecksFailed).Field2Checks
+	failures := make(map[string][]string, len(field2checks))
+	for field, checks := range field2checks {
+		failures[field.Name] = checks
+	}
+	return failures
+}
@@ -0,0 +1,280 @@
+package structcheck
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Fills struct v (a non-nil pointer) from form values and records which fields were present. Inputs are named by the "form" tag, or by the field name if there's no tag; a tag of "-" skips the field. Fields of nested structs are named parent.child. Slices take every value of their input. Inputs that can't be parsed are reported together in an ErrorFormDecode after the rest of v is filled.
+func DecodeForm(values url.Values, v interface{}) (*Presence, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, fmt.Errorf("DecodeForm requires a non-nil pointer. Received: %T", v)
+	}
+	rv, err := drillDown(rv)
+	if err != nil {
+		return nil, err
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, ErrorInvalidKind{Type: rv.Type()}
+	}
+	p := newPresence(nil)
+	invalid := make(map[string]error)
+	decodeForm(values, rv, "", structScope{t: rv.Type()}, p, invalid)
+	if len(invalid) != 0 {
+		return p, ErrorFormDecode{Inputs: invalid}
+	}
+	return p, nil
+}
+
+// the FormErrors key of failures on fields that have no form input (e.g. fields tagged form:"-"). Its messages are prefixed with the field's path.
+const NonFieldErrors = "__all__"
+
+// Decodes form values into v like DecodeForm and validates it with presence information. Failures are returned as messages keyed by input name (see DefaultMessages and MessageTag), or under NonFieldErrors; the error is only non-nil if validation couldn't run.
+func DecodeAndValidateForm(values url.Values, v interface{}, opts ...Option) (FormErrors, error) {
+	errs := FormErrors{}
+	p, err := DecodeForm(values, v)
+	if decodeErr, ok := err.(ErrorFormDecode); ok {
+		for input := range decodeErr.Inputs {
+			errs.Add(input, "is not valid")
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	err = Validate(v, append(opts[:len(opts):len(opts)], WithPresence(p))...)
+	checksFailed, ok := err.(ErrorChecksFailed)
+	if err != nil && !ok {
+		return nil, err
+	}
+	inputs := formInputs(reflect.TypeOf(v))
+	for field, checks := range checksFailed.Field2Checks {
+		input, ok := formInputFor(inputs, field.Path)
+		if !ok {
+			for i := range checks {
+				errs.Add(NonFieldErrors, fmt.Sprintf("%v: %v", field.Path, checksFailed.message(field, i)))
+			}
+			continue
+		}
+		if _, invalid := errs[input]; invalid {
+			continue
+		}
+		for i := range checks {
+			errs.Add(input, checksFailed.message(field, i))
+		}
+	}
+	return errs, nil
+}
+
+func decodeForm(values url.Values, v reflect.Value, prefix string, scope structScope, p *Presence, invalid map[string]error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := formInputName(f, prefix)
+		if !ok {
+			continue
+		}
+		fieldPath, qualifiedPath, fieldScope := scope.field(i)
+		field := v.Field(i)
+		if isFormStruct(f.Type) {
+			if field.Kind() == reflect.Ptr {
+				if !hasFormPrefix(values, name+".") {
+					continue
+				}
+				if field.IsNil() {
+					field.Set(reflect.New(f.Type.Elem()))
+				}
+				field = field.Elem()
+			}
+			decodeForm(values, field, name+".", fieldScope, p, invalid)
+			continue
+		}
+		inputs, ok := values[name]
+		if !ok {
+			continue
+		}
+		if err := setFromStrings(field, inputs); err != nil {
+			invalid[name] = err
+			continue
+		}
+		p.record(fieldPath, qualifiedPath, 0)
+	}
+}
+
+// returns the input name of struct field f, or false if the field isn't decoded from forms
+func formInputName(f reflect.StructField, prefix string) (string, bool) {
+	name := f.Tag.Get("form")
+	if name == "-" || f.PkgPath != "" {
+		return "", false
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return prefix + name, true
+}
+
+// maps the paths of the fields of type t to the names of their inputs. Fields without inputs map to "".
+func formInputs(t reflect.Type) map[string]string {
+	inputs := make(map[string]string)
+	var walk func(t reflect.Type, prefix string, scope structScope, depth int)
+	walk = func(t reflect.Type, prefix string, scope structScope, depth int) {
+		t = derefType(t)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, ok := formInputName(f, prefix)
+			fieldPath, qualifiedPath, fieldScope := scope.field(i)
+			if !ok {
+				inputs[joinName(fieldPath)] = ""
+				inputs[joinName(qualifiedPath)] = ""
+				continue
+			}
+			inputs[joinName(fieldPath)] = name
+			inputs[joinName(qualifiedPath)] = name
+			if isFormStruct(f.Type) && depth < maxFormDepth {
+				walk(f.Type, name+".", fieldScope, depth+1)
+			}
+		}
+	}
+	walk(t, "", structScope{t: t}, 0)
+	return inputs
+}
+
+// bounds the nesting of recursive types in forms
+const maxFormDepth = 32
+
+// returns the input name for the field at path, using the closest named ancestor for elements and unnamed fields. Returns false if the field has no input.
+func formInputFor(inputs map[string]string, path string) (string, bool) {
+	segments, _ := splitPath(path)
+	for i := len(segments); i > 0; i-- {
+		if input, ok := inputs[joinName(segments[:i])]; ok {
+			return input, input != ""
+		}
+	}
+	return "", false
+}
+
+// Validation failures keyed by form input name. Its methods can be called from html/template, e.g. {{with .Errors.Get "email"}}<span class="error">{{.}}</span>{{end}}.
+type FormErrors map[string][]string
+
+func (e FormErrors) Add(input, message string) {
+	e[input] = append(e[input], message)
+}
+
+// true if the input has any errors
+func (e FormErrors) Has(input string) bool {
+	return len(e[input]) != 0
+}
+
+// returns the first message for the input, or ""
+func (e FormErrors) Get(input string) string {
+	if len(e[input]) == 0 {
+		return ""
+	}
+	return e[input][0]
+}
+
+// returns all messages for the input
+func (e FormErrors) All(input string) []string {
+	return e[input]
+}
+
+// true if values of type t are decoded field by field rather than from a single input
+func isFormStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !t.Implements(textUnmarshalerType) && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func hasFormPrefix(values url.Values, prefix string) bool {
+	for name := range values {
+		if len(name) > len(prefix) && name[:len(prefix)] == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// sets v from one or more string inputs. Slices take all inputs; everything else takes the first.
+func setFromStrings(v reflect.Value, inputs []string) error {
+	if v.Kind() == reflect.Slice && !v.Type().Implements(textUnmarshalerType) && v.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(v.Type(), len(inputs), len(inputs))
+		for i, input := range inputs {
+			if err := setFromString(slice.Index(i), input); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+	return setFromString(v, inputs[0])
+}
+
+// parses s into v according to v's type
+func setFromString(v reflect.Value, s string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setFromString(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("Unsupported type: %v", v.Type())
+		}
+		v.SetBytes([]byte(s))
+	default:
+		return fmt.Errorf("Unsupported type: %v", v.Type())
+	}
+	return nil
+}
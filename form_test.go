@@ -0,0 +1,98 @@
+package structcheck
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"html/template"
+	"net/url"
+	"testing"
+)
+
+type formTestSignup struct {
+	Email    string   `form:"email" checks:"NotEmpty"`
+	Age      int      `form:"age" checks:"Positive"`
+	Interest []string `form:"interest" checks:"NotEmpty"`
+	Address  *struct {
+		Zip string `form:"zip" checks:"Required"`
+	} `form:"address"`
+	Internal string `form:"-" checks:"Empty"`
+}
+
+func TestDecodeForm(t *testing.T) {
+	var o formTestSignup
+	p, err := DecodeForm(url.Values{
+		"email":       {"a@example.com"},
+		"age":         {"30"},
+		"interest":    {"go", "forms"},
+		"address.zip": {"12345"},
+		"Internal":    {"x"},
+	}, &o)
+	require.NoError(t, err)
+	require.Equal(t, "a@example.com", o.Email)
+	require.Equal(t, 30, o.Age)
+	require.Equal(t, []string{"go", "forms"}, o.Interest)
+	require.Equal(t, "12345", o.Address.Zip)
+	require.Equal(t, "", o.Internal)
+	require.Equal(t, []string{"Address.Zip", "Age", "Email", "Interest"}, p.Paths())
+}
+
+func TestDecodeAndValidateForm(t *testing.T) {
+	var o formTestSignup
+	errs, err := DecodeAndValidateForm(url.Values{
+		"age":          {"old"},
+		"address.city": {"Springfield"},
+	}, &o)
+	require.NoError(t, err)
+	require.Equal(t, FormErrors{
+		"email":       {"must not be empty"},
+		"age":         {"is not valid"},
+		"interest":    {"must not be empty"},
+		"address.zip": {"is required"},
+	}, errs)
+
+	tmpl := template.Must(template.New("").Parse(`{{if .Has "email"}}<span>{{.Get "email"}}</span>{{end}}{{.Get "nothing"}}`))
+	buf := new(bytes.Buffer)
+	require.NoError(t, tmpl.Execute(buf, errs))
+	require.Equal(t, "<span>must not be empty</span>", buf.String())
+}
+
+func TestDecodeAndValidateForm_nonField(t *testing.T) {
+	o := formTestSignup{Internal: "x"}
+	errs, err := DecodeAndValidateForm(url.Values{"email": {"a@example.com"}, "age": {"1"}, "interest": {"go"}}, &o)
+	require.NoError(t, err)
+	require.Equal(t, FormErrors{NonFieldErrors: {"Internal: must be empty"}}, errs)
+
+	var nested struct {
+		Address struct {
+			Zip    string `form:"zip"`
+			Secret string `form:"-" checks:"NotEmpty"`
+		} `form:"address"`
+	}
+	errs, err = DecodeAndValidateForm(url.Values{"address.zip": {"1"}}, &nested)
+	require.NoError(t, err)
+	require.Equal(t, FormErrors{NonFieldErrors: {"Address.Secret: must not be empty"}}, errs)
+}
+
+func TestDecodeAndValidateForm_good(t *testing.T) {
+	var o formTestSignup
+	errs, err := DecodeAndValidateForm(url.Values{"email": {"a@example.com"}, "age": {"1"}, "interest": {"go"}}, &o)
+	require.NoError(t, err)
+	require.Empty(t, errs)
+}
+
+type FormTestContact struct {
+	Phone string `form:"phone" checks:"Required"`
+}
+
+func TestDecodeAndValidateForm_embedded(t *testing.T) {
+	var o struct {
+		FormTestContact `form:"contact"`
+		Name            string `form:"name" checks:"NotEmpty"`
+	}
+	errs, err := DecodeAndValidateForm(url.Values{"name": {"a"}}, &o)
+	require.NoError(t, err)
+	require.Equal(t, FormErrors{"contact.phone": {"is required"}}, errs)
+	errs, err = DecodeAndValidateForm(url.Values{"name": {"a"}, "contact.phone": {"1"}}, &o)
+	require.NoError(t, err)
+	require.Empty(t, errs)
+}
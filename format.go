@@ -0,0 +1,206 @@
+package structcheck
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// renders a failing value for Field.Value
+type ValueFormatter func(v reflect.Value) string
+
+// A ValueFormatter that renders values with %#v, keeping large values readable. Values of types in Types use their own formatter; other values implementing fmt.Stringer or encoding.TextMarshaler are rendered as Type(text).
+type Formatter struct {
+	MaxLen      int                             // renderings longer than this many characters are truncated with an ellipsis, and aren't rendered past it (0 means unlimited)
+	MaxElements int                             // slices, arrays and maps with more elements are summarized by their length, at any depth (0 means unlimited)
+	Types       map[reflect.Type]ValueFormatter // formatters for specific types, whose output isn't truncated. They're only given values that allow Interface() (not ones read from unexported fields).
+}
+
+// the formatter used unless WithFormatter is given
+var DefaultFormatter = Formatter{MaxLen: 100, MaxElements: 16}
+
+var (
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (f Formatter) Format(v reflect.Value) string {
+	if !v.CanInterface() {
+		return f.truncate(f.render(v))
+	}
+	if format, ok := f.Types[v.Type()]; ok {
+		return format(v)
+	}
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		if format, ok := f.Types[v.Type().Elem()]; ok {
+			return format(v.Elem())
+		}
+	}
+	return f.truncate(f.render(v))
+}
+
+// renders v like %#v, but stops once MaxLen is exceeded and summarizes containers at any depth
+func (f Formatter) render(v reflect.Value) string {
+	if !isNilValue(v) {
+		// methods with pointer receivers are used when v is addressable, as with Redactor
+		if m, ok := asImplementer(v, textMarshalerType); ok {
+			if text, err := m.(encoding.TextMarshaler).MarshalText(); err == nil {
+				return fmt.Sprintf("%v(%s)", v.Type(), text)
+			}
+		} else if s, ok := asImplementer(v, stringerType); ok {
+			return fmt.Sprintf("%v(%v)", v.Type(), s.(fmt.Stringer).String())
+		}
+	}
+	w := &boundedWriter{max: f.MaxLen}
+	f.write(w, v, 0)
+	return w.String()
+}
+
+var goStringerType = reflect.TypeOf((*fmt.GoStringer)(nil)).Elem()
+
+// writes v to w the way %#v would at depth (pointers below the top are printed as addresses)
+func (f Formatter) write(w *boundedWriter, v reflect.Value, depth int) {
+	if w.full {
+		return
+	}
+	if v.CanInterface() && v.Type().Implements(goStringerType) && !isNilValue(v) {
+		w.write(v.Interface().(fmt.GoStringer).GoString())
+		return
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			w.write(fmt.Sprintf("%#v", v))
+			return
+		}
+		f.write(w, v.Elem(), depth+1)
+	case reflect.Ptr:
+		switch v.Type().Elem().Kind() {
+		case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map:
+			if depth == 0 && !v.IsNil() {
+				w.write("&")
+				f.write(w, v.Elem(), depth+1)
+				return
+			}
+		}
+		w.write(fmt.Sprintf("%#v", v))
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if isNilValue(v) {
+			w.write(fmt.Sprintf("%#v", v))
+			return
+		}
+		if f.MaxElements > 0 && v.Len() > f.MaxElements {
+			w.write(fmt.Sprintf("%v{len: %d}", v.Type(), v.Len()))
+			return
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 && v.Kind() != reflect.Map {
+			// byte slices and arrays are printed in hex, which %#v does more compactly
+			if v.Kind() == reflect.Slice && w.max > 0 && v.Len() > w.max {
+				v = v.Slice(0, w.max)
+			}
+			w.write(fmt.Sprintf("%#v", v))
+			return
+		}
+		w.write(v.Type().String() + "{")
+		if v.Kind() == reflect.Map {
+			f.writeMap(w, v, depth)
+		} else {
+			for i := 0; i < v.Len() && !w.full; i++ {
+				if i != 0 {
+					w.write(", ")
+				}
+				f.write(w, v.Index(i), depth+1)
+			}
+		}
+		w.write("}")
+	case reflect.Struct:
+		w.write(v.Type().String() + "{")
+		for i := 0; i < v.NumField() && !w.full; i++ {
+			if i != 0 {
+				w.write(", ")
+			}
+			w.write(v.Type().Field(i).Name + ":")
+			f.write(w, v.Field(i), depth+1)
+		}
+		w.write("}")
+	case reflect.String:
+		// only the start of a long string can be shown
+		if s := v.String(); w.max > 0 && len(s) > 4*w.max {
+			w.write(fmt.Sprintf("%#v", s[:4*w.max]))
+			return
+		}
+		w.write(fmt.Sprintf("%#v", v))
+	default:
+		w.write(fmt.Sprintf("%#v", v))
+	}
+}
+
+// writes the entries of map v, ordered by their keys' renderings
+func (f Formatter) writeMap(w *boundedWriter, v reflect.Value, depth int) {
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		key := &boundedWriter{max: w.max}
+		f.write(key, iter.Key(), depth+1)
+		entries = append(entries, entry{key: key.String(), value: iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	for i, e := range entries {
+		if w.full {
+			return
+		}
+		if i != 0 {
+			w.write(", ")
+		}
+		w.write(e.key + ":")
+		f.write(w, e.value, depth+1)
+	}
+}
+
+// collects a rendering until it's longer than max runes (0 means unlimited), leaving the rest for truncate to cut
+type boundedWriter struct {
+	strings.Builder
+	max   int
+	runes int
+	full  bool // more than max runes were written, so the rest is dropped
+}
+
+func (w *boundedWriter) write(s string) {
+	if w.full {
+		return
+	}
+	n := utf8.RuneCountInString(s)
+	if w.max > 0 && w.runes+n > w.max {
+		// keep one rune past max so that truncate knows to add an ellipsis
+		keep := w.max - w.runes + 1
+		for i := range s {
+			if keep == 0 {
+				s = s[:i]
+				break
+			}
+			keep--
+		}
+		w.full = true
+	}
+	w.WriteString(s)
+	w.runes += n
+}
+
+func (f Formatter) truncate(s string) string {
+	if f.MaxLen <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= f.MaxLen {
+		return s
+	}
+	return string(runes[:f.MaxLen]) + "…"
+}
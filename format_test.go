@@ -0,0 +1,115 @@
+package structcheck
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type formatPoint struct {
+	X, Y int
+}
+
+func TestFormatter(t *testing.T) {
+	f := DefaultFormatter
+	for _, tc := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{3, "3"},
+		{"abc", `"abc"`},
+		{[]int(nil), "[]int(nil)"},
+		{(*int)(nil), "(*int)(nil)"},
+		{formatPoint{1, 2}, "structcheck.formatPoint{X:1, Y:2}"},
+		{[]int{1, 2, 3}, "[]int{1, 2, 3}"},
+		{make([]int, 1000), "[]int{len: 1000}"},
+		{map[string]int{"a": 1}, `map[string]int{"a":1}`},
+		{make([]byte, 20), "[]uint8{len: 20}"},
+		{5 * time.Second, "time.Duration(5s)"},
+		{net.IPv4(10, 0, 0, 1), "net.IP(10.0.0.1)"},
+		{strings.Repeat("x", 200), `"` + strings.Repeat("x", 99) + "…"},
+		{&formatPoint{1, 2}, "&structcheck.formatPoint{X:1, Y:2}"},
+		{[]interface{}{1, "a", nil}, `[]interface {}{1, "a", interface {}(nil)}`},
+		{map[string][]int{"b": {2}, "a": make([]int, 100)}, `map[string][]int{"a":[]int{len: 100}, "b":[]int{2}}`},
+		{formatWrapper{Items: make([]int, 1000)}, "structcheck.formatWrapper{Items:[]int{len: 1000}, At:time.Date(1, time.January, 1, 0, 0, 0, 0, time.…"},
+	} {
+		assert.Equal(t, tc.expected, f.Format(reflect.ValueOf(tc.value)))
+	}
+}
+
+type formatWrapper struct {
+	Items []int
+	At    time.Time
+}
+
+func TestFormatterStopsAtMaxLen(t *testing.T) {
+	huge := map[string]interface{}{"items": make([]string, 1000000)}
+	f := Formatter{MaxLen: 30}
+	start := time.Now()
+	// nothing past MaxLen is rendered, even without MaxElements
+	assert.Equal(t, `map[string]interface {}{"items…`, f.Format(reflect.ValueOf(huge)))
+	assert.Equal(t, `structcheck.formatWrapper{Item…`, f.Format(reflect.ValueOf(formatWrapper{Items: make([]int, 1000000)})))
+	assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond))
+}
+
+func TestFormatterTypes(t *testing.T) {
+	f := Formatter{Types: map[reflect.Type]ValueFormatter{
+		reflect.TypeOf(formatPoint{}): func(v reflect.Value) string {
+			return fmt.Sprintf("(%d, %d)", v.Field(0).Int(), v.Field(1).Int())
+		},
+	}}
+	assert.Equal(t, "(1, 2)", f.Format(reflect.ValueOf(formatPoint{1, 2})))
+	assert.Equal(t, "(1, 2)", f.Format(reflect.ValueOf(&formatPoint{1, 2})))
+	assert.Equal(t, strings.Repeat("a", 200), Formatter{}.Format(reflect.ValueOf(strings.Repeat("a", 200)))[1:201])
+}
+
+type formatPtrStringer struct {
+	N int
+}
+
+func (s *formatPtrStringer) String() string {
+	return fmt.Sprintf("#%d", s.N)
+}
+
+func TestFormatterPointerReceivers(t *testing.T) {
+	// addressable values use methods with pointer receivers
+	assert.Equal(t, "structcheck.formatPtrStringer(#1)", DefaultFormatter.Format(reflect.ValueOf(&formatPtrStringer{1}).Elem()))
+	assert.Equal(t, "structcheck.formatPtrStringer{N:1}", DefaultFormatter.Format(reflect.ValueOf(formatPtrStringer{1})))
+}
+
+func TestFormatterTypesUnexported(t *testing.T) {
+	f := Formatter{Types: map[reflect.Type]ValueFormatter{
+		reflect.TypeOf(formatPoint{}): func(v reflect.Value) string {
+			return fmt.Sprint(v.Interface())
+		},
+	}}
+	err := Validate(struct {
+		Public  formatPoint `checks:"NotZero"`
+		private formatPoint `checks:"NotZero"`
+	}{}, WithFormatter(f.Format))
+	// values read from unexported fields aren't given to Types
+	assert.Equal(t, map[string]string{
+		"Public":  "{0 0}",
+		"private": "unexported field (structcheck.formatPoint{X:0, Y:0})",
+	}, valuesByPath(t, err))
+}
+
+func TestWithFormatter(t *testing.T) {
+	s := struct {
+		Data   []int  `checks:"Empty"`
+		Secret string `checks:"sensitive,Empty"`
+	}{Data: make([]int, 100), Secret: "s3cret"}
+	assert.Equal(t, map[string]string{
+		"Data":   "[]int{len: 100}",
+		"Secret": RedactedValue,
+	}, valuesByPath(t, Validate(s)))
+	short := Formatter{MaxLen: 5}
+	assert.Equal(t, map[string]string{
+		"Data":   "[]int…",
+		"Secret": RedactedValue,
+	}, valuesByPath(t, Validate(s, WithFormatter(short.Format))))
+}
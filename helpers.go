@@ -5,49 +5,170 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type CheckFinder func(v metaValue) ([]Check, []string, error)
 
 func BuildTagCheckFinder(checkSet map[string]Check) CheckFinder {
 	return func(v metaValue) ([]Check, []string, error) {
+		if v.lookup != nil {
+			return lookupChecks(v, checkSet)
+		}
 		return tagCheckFinder(v, checkSet)
 	}
 }
 
+// Builds a CheckFinder like BuildTagCheckFinder that parses each distinct tag once. checkSet must not change while the finder is in use.
+func buildCachedTagCheckFinder(checkSet map[string]Check) CheckFinder {
+	cache := sync.Map{} // tag -> []tagEntry
+	return func(v metaValue) ([]Check, []string, error) {
+		if v.lookup != nil {
+			return lookupChecks(v, checkSet)
+		}
+		if v.tag == nil {
+			return []Check{}, []string{}, nil
+		}
+		tag := v.tag.Get("checks")
+		entries, ok := cache.Load(tag)
+		if !ok {
+			entries, _ = cache.LoadOrStore(tag, parseTagEntries(tag, checkSet))
+		}
+		return selectTagChecks(v, entries.([]tagEntry))
+	}
+}
+
 // Searches struct field tags for check directives
 func tagCheckFinder(v metaValue, checkSet map[string]Check) ([]Check, []string, error) {
+	if v.tag == nil {
+		return []Check{}, []string{}, nil
+	}
+	return selectTagChecks(v, parseTagEntries(v.tag.Get("checks"), checkSet))
+}
+
+// a check named in a checks tag
+type tagEntry struct {
+	checkToken
+	check  Check
+	reason string // why the check is illegal, if it is
+}
+
+func parseTagEntries(tag string, checkSet map[string]Check) []tagEntry {
+	entries := []tagEntry{}
+	for _, str := range strings.Split(tag, ",") {
+		if str == "" || isDirective(str) {
+			continue
+		}
+		entry := tagEntry{checkToken: parseCheckToken(str)}
+		check, err := lookupCheck(entry.name, checkSet)
+		if err != nil {
+			entry.reason = err.Error()
+		} else if check == nil {
+			entry.reason = fmt.Sprintf("'%v' is not a recognized check type", entry.name)
+		}
+		entry.check = check
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// returns the checks of entries in v's active groups. Illegal checks are only reported if they would run.
+func selectTagChecks(v metaValue, entries []tagEntry) ([]Check, []string, error) {
 	checks := []Check{}
 	checkNames := []string{}
-	if v.tag != nil {
-		for _, str := range strings.Split(v.tag.Get("checks"), ",") {
-			if str == "" {
-				continue
-			}
-			check, ok := checkSet[str]
-			if ok {
-				checks = append(checks, check)
-				checkNames = append(checkNames, str)
-			} else {
-				return nil, nil, ErrorIllegalCheck{
-					value:  v,
-					Reason: fmt.Sprintf("'%v' is not a recognized check type", str),
-				}
+	for _, entry := range entries {
+		if v.opts != nil && !v.opts.groupsActive(entry.groups) {
+			continue
+		}
+		if entry.reason != "" {
+			return nil, nil, ErrorIllegalCheck{
+				value:  v,
+				Reason: entry.reason,
 			}
 		}
+		checks = append(checks, entry.check)
+		checkNames = append(checkNames, entry.name)
 	}
 	return checks, checkNames, nil
 }
 
+// Reserved entries of a checks tag that control the traversal instead of naming checks. They apply whichever CheckFinder is used.
+const (
+	DirectiveSkip        = "-"           // the field is ignored entirely
+	DirectiveNoDescend   = "nodescend"   // the field is checked, but nothing below it is
+	DirectiveNoTagsBelow = "notagsbelow" // the checks tags of fields below the field are ignored
+	DirectiveSensitive   = "sensitive"   // the values of the field and everything below it are redacted from failures
+)
+
+func isDirective(str string) bool {
+	return str == DirectiveSkip || str == DirectiveNoDescend || str == DirectiveNoTagsBelow || str == DirectiveSensitive
+}
+
+// true if tag's checks include the directive
+func hasDirective(tag reflect.StructTag, directive string) bool {
+	return containsString(strings.Split(tag.Get("checks"), ","), directive)
+}
+
+// a single entry of a checks tag: CheckName[@group1|group2...]
+type checkToken struct {
+	name   string
+	groups []string
+}
+
+func parseCheckToken(str string) checkToken {
+	token := checkToken{name: str}
+	if i := strings.Index(str, "@"); i != -1 {
+		token.name = str[:i]
+		token.groups = strings.Split(str[i+1:], "|")
+	}
+	return token
+}
+
 // value plus information from a few levels up
 type metaValue struct {
 	reflect.Value
 	Name   []string
+	Types  []reflect.Type // the type of the node named by each segment of Name
 	Number []int
 	CheckFinder
-	tag *reflect.StructTag
+	tag        *reflect.StructTag
+	opts       *options
+	pointee    bool        // true if v was reached by dereferencing a pointer
+	elemChecks []elemCheck // checks waiting for the end of the pointer chain that led to v
+	ancestors  *pointerChain
+	unexported bool       // v is an unexported field (or the value it points to)
+	embedding  *embedding // set if v is an embedded struct (or pointer to one) whose fields are promoted
+	noDescend  bool       // the traversal stops at v (see DirectiveNoDescend)
+	noTags     bool       // the tags of fields below v are ignored (see DirectiveNoTagsBelow)
+	sensitive  bool       // v or one of its parents is tagged sensitive (see DirectiveSensitive)
+	lookup     []string   // if set, the CheckFinder resolves these check names against its check set instead of finding v's checks (see rootChecks)
+	// v is a pointer, slice or map referring to one of its own ancestors (a cycle) or already reached below an alias, or the value such a pointer points to. Only the checks of the field that reached it again run, and its children aren't explored.
+	visited         bool
+	unexpectedAlias bool       // v is a pointer to a value of an exclusively owned type that was already reached through another field
+	alias           *aliasRoot // set if v is (or is below) a pointer, slice or map that was already reached through another field
+}
+
+// a reference reached through more than one field. The values below other are explored again so that other's checks run on them, up to the next reference that was already reached.
+type aliasRoot struct {
+	first string // the name of the field that reached the reference first
+	other string // the name of the field that reached it again
+}
+
+// the name the value named name was first reached under, if it's strictly below the reference and the name can be derived (i.e. it isn't promoted from an embedded struct)
+func (a *aliasRoot) firstName(name string) (string, bool) {
+	if name == a.other || !hasPathPrefix(name, a.other) {
+		return "", false
+	}
+	return a.first + name[len(a.other):], true
+}
+
+// a check waiting for the value at the end of a chain of pointers
+type elemCheck struct {
+	namedCheck
+	strict bool // fails if the chain ends in a nil pointer
 }
 
 func (v metaValue) buildDeeperName(n string) []string {
@@ -56,81 +177,333 @@ func (v metaValue) buildDeeperName(n string) []string {
 	return append(name, n)
 }
 
+func (v metaValue) buildDeeperTypes(t reflect.Type) []reflect.Type {
+	types := make([]reflect.Type, len(v.Types), len(v.Types)+1)
+	copy(types, v.Types)
+	return append(types, t)
+}
+
 func (v metaValue) buildDeeperNumber(n int) []int {
 	num := make([]int, len(v.Number), len(v.Number)+1)
 	copy(num, v.Number)
 	return append(num, n)
 }
 
+// builds a child node one segment below v
+func (v metaValue) deeper(value reflect.Value, name string, number int) metaValue {
+	child := v
+	child.Value = value
+	child.Name = v.buildDeeperName(name)
+	child.Types = v.buildDeeperTypes(value.Type())
+	child.Number = v.buildDeeperNumber(number)
+	child.tag = nil
+	child.pointee = false
+	child.elemChecks = nil
+	child.unexpectedAlias = false
+	child.unexported = false
+	child.embedding = nil
+	child.noDescend = false
+	return child
+}
+
 func (v metaValue) Field(i int) metaValue {
 	f := v.Value.Type().Field(i)
-	return metaValue{
-		Value:       v.Value.Field(i),
-		Name:        v.buildDeeperName(f.Name),
-		Number:      v.buildDeeperNumber(i),
-		CheckFinder: v.CheckFinder,
-		tag:         &f.Tag,
+	child := v.deeper(v.Value.Field(i), f.Name, i)
+	if !v.noTags {
+		child.tag = &f.Tag
+		child.noDescend = hasDirective(f.Tag, DirectiveNoDescend)
+		child.noTags = hasDirective(f.Tag, DirectiveNoTagsBelow)
+	}
+	// redaction is honored even below notagsbelow
+	child.sensitive = v.sensitive || hasDirective(f.Tag, DirectiveSensitive)
+	child.unexported = isUnexported(f)
+	if v.opts.keepsEmbeddedNames() {
+		return child
+	}
+	// fields of embedded structs are named as fields of the struct embedding them, unless they're hidden by another field with the same name
+	scope := v.embedding
+	if scope == nil {
+		scope = &embedding{Type: v.Type(), name: v.Name, types: v.Types}
+	}
+	index := appendIndex(scope.index, i)
+	if v.embedding != nil && isPromoted(scope.Type, f.Name, index) {
+		child.Name = appendPath(scope.name, f.Name)
+		child.Types = make([]reflect.Type, len(scope.types), len(scope.types)+1)
+		copy(child.Types, scope.types)
+		child.Types = append(child.Types, f.Type)
+	}
+	if isEmbeddedStruct(f) {
+		child.embedding = &embedding{Type: scope.Type, name: scope.name, types: scope.types, index: index}
+	}
+	return child
+}
+
+// the struct that the fields of an embedded struct are promoted to
+type embedding struct {
+	reflect.Type
+	name  []string
+	types []reflect.Type
+	index []int // the index of the embedded struct within Type
+}
+
+// true if f is an unexported field. Embedded structs aren't counted because their exported fields are promoted.
+func isUnexported(f reflect.StructField) bool {
+	return f.PkgPath != "" && !f.Anonymous
+}
+
+// Index returns the i'th element of v (assuming v is a slice or array)
+func (v metaValue) Index(i int) metaValue {
+	return v.deeper(v.Value.Index(i), fmt.Sprintf("[%d]", i), i)
+}
+
+// MapEntries returns the values of v (assuming v is a map), ordered by key
+func (v metaValue) MapEntries() []metaValue {
+	// MapIndex can't find NaN keys, so entries are read with MapRange
+	type entry struct {
+		name  string
+		value reflect.Value
+	}
+	all := make([]entry, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		all = append(all, entry{name: v.keySegment(iter.Key()), value: iter.Value()})
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].name < all[j].name
+	})
+	entries := make([]metaValue, len(all))
+	for i, e := range all {
+		entries[i] = v.deeper(e.value, e.name, i)
+	}
+	return entries
+}
+
+// names a map entry. In untyped mode, string keys that are valid path segments act as field names.
+func (v metaValue) keySegment(key reflect.Value) string {
+	if v.opts != nil && v.opts.untyped && key.Kind() == reflect.String {
+		if k := key.String(); k != "" && !strings.ContainsAny(k, ".[]()*") {
+			return k
+		}
 	}
+	return fmt.Sprintf("[%v]", key)
 }
 
-// InterfaceValue returns the Value wrapped by v (assuming v is an interface)
+// InterfaceValue returns the Value wrapped by v (assuming v is a non-nil interface)
 func (v metaValue) InterfaceValue() metaValue {
-	v2 := reflect.ValueOf(v.Value.Interface())
-	n := v.buildDeeperName(fmt.Sprintf("(%v)", v2.Type().Name()))
-	return metaValue{
-		Value:       v2,
-		Name:        n,
-		Number:      v.Number,
-		CheckFinder: v.CheckFinder,
-		tag:         v.tag,
+	v2 := v.Value.Elem()
+	child := v
+	child.Value = v2
+	if v.opts != nil && v.opts.untyped {
+		// dynamic values are named by where they are, not by their type
+		child.Types = make([]reflect.Type, len(v.Types))
+		copy(child.Types, v.Types)
+		child.Types[len(child.Types)-1] = v2.Type()
+		return child
 	}
+	child.Name = v.buildDeeperName(fmt.Sprintf("(%v)", v2.Type().Name()))
+	child.Types = v.buildDeeperTypes(v2.Type())
+	return child
 }
 
 func (v metaValue) Indirect() metaValue {
-	return metaValue{
-		Value:       reflect.Indirect(v.Value),
-		Name:        v.Name,
-		Number:      v.Number,
-		CheckFinder: v.CheckFinder,
-		tag:         v.tag,
+	child := v
+	child.Value = reflect.Indirect(v.Value)
+	child.pointee = true
+	child.elemChecks = nil
+	child.unexpectedAlias = false
+	return child
+}
+
+// true if the traversal descends into the elements of containers of type t. Byte slices and arrays are treated as opaque values.
+func traversesElements(t reflect.Type) bool {
+	return t.Elem().Kind() != reflect.Uint8
+}
+
+// lists the nodes directly below v
+func (v metaValue) children() []metaValue {
+	children := []metaValue{}
+	if (v.visited || v.noDescend) && v.Kind() != reflect.Ptr {
+		return children
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			children = append(children, v.Indirect())
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			children = append(children, v.InterfaceValue())
+		}
+	case reflect.Struct:
+		skipUnexported := v.opts.unexportedPolicy() == UnexportedSkip
+		for j := 0; j < v.NumField(); j++ {
+			f := v.Type().Field(j)
+			if skipUnexported && isUnexported(f) {
+				continue
+			}
+			if !v.noTags && hasDirective(f.Tag, DirectiveSkip) {
+				continue
+			}
+			children = append(children, v.Field(j))
+		}
+	case reflect.Slice, reflect.Array:
+		if traversesElements(v.Type()) {
+			for j := 0; j < v.Len(); j++ {
+				children = append(children, v.Index(j))
+			}
+		}
+	case reflect.Map:
+		if traversesElements(v.Type()) {
+			children = append(children, v.MapEntries()...)
+		}
 	}
+	return children
 }
 
 func (v metaValue) getChecks() ([]Check, []string, error) {
 	return v.CheckFinder(v)
 }
 
-// Breadth First Search queue for reflective struct exploration. Prevents infinite recursion by marking pointers and refusing to push marked pointers.
+// resolves the names passed to WithRootChecks with v's CheckFinder, so they come from the same check set as the checks of fields
+func (v metaValue) rootChecks() ([]namedCheck, error) {
+	lookup := v
+	lookup.lookup = v.opts.root
+	checks, names, err := v.CheckFinder(lookup)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make([]namedCheck, 0, len(v.opts.root))
+	for _, name := range v.opts.root {
+		found := false
+		for i := range names {
+			if names[i] == name {
+				resolved = append(resolved, namedCheck{name: name, check: checks[i]})
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrorIllegalCheck{value: v, Reason: fmt.Sprintf("'%v' is not a recognized check type", name)}
+		}
+	}
+	return resolved, nil
+}
+
+// the checks named by v.lookup that checkSet (or DefaultParamChecks) resolves. Names it doesn't know are left out.
+func lookupChecks(v metaValue, checkSet map[string]Check) ([]Check, []string, error) {
+	checks := []Check{}
+	checkNames := []string{}
+	for _, name := range v.lookup {
+		check, err := lookupCheck(name, checkSet)
+		if err != nil {
+			return nil, nil, ErrorIllegalCheck{value: v, Reason: err.Error()}
+		}
+		if check != nil {
+			checks = append(checks, check)
+			checkNames = append(checkNames, name)
+		}
+	}
+	return checks, checkNames, nil
+}
+
+// copies a check set that is read after the CheckFinder using it was built
+func copyCheckSet(checkSet map[string]Check) map[string]Check {
+	copied := make(map[string]Check, len(checkSet))
+	for name, check := range checkSet {
+		copied[name] = check
+	}
+	return copied
+}
+
+// Breadth First Search queue for reflective struct exploration. Prevents infinite recursion by marking pointers, slices and maps: one that is reached again through a cycle is checked, but the value it refers to isn't explored again. One that is reached again through another field is explored again below that field, up to the references that were already reached, so each shared value is walked once per field that reaches it.
 type valueQueue struct {
-	queuedPointers map[uintptr]interface{}
+	queuedPointers map[visitKey]string // the name of the first field that reached each pointer
 	queue          *list.List
 }
 
 func newValueQueue() *valueQueue {
 	return &valueQueue{
-		queuedPointers: make(map[uintptr]interface{}),
+		queuedPointers: make(map[visitKey]string),
 		queue:          list.New(),
 	}
 }
 
 func (q *valueQueue) Push(v metaValue) {
+	q.queue.PushBack(q.admit(v))
+}
+
+// unwraps interfaces and marks pointers, slices and maps
+func (q *valueQueue) admit(v metaValue) metaValue {
 	kind := v.Kind()
 	// take internal value of interfaces
-	if kind == reflect.Interface {
+	if kind == reflect.Interface && !v.IsNil() {
 		v = v.InterfaceValue()
+		kind = v.Kind()
 	}
-	// mark pointers
-	if kind == reflect.Ptr && !v.IsNil() {
-		ptr := v.Pointer()
-		if _, present := q.queuedPointers[ptr]; present {
-			return
+	// mark references
+	if key, ok := newVisitKey(v.Value); ok {
+		if v.ancestors.contains(key) {
+			v.visited = true
+		} else if first, present := q.queuedPointers[key]; present {
+			if v.alias == nil {
+				v.alias = &aliasRoot{first: first, other: joinName(v.Name)}
+				v.unexpectedAlias = kind == reflect.Ptr && v.opts != nil && v.opts.exclusive[v.Type().Elem()]
+			} else {
+				// below an alias, references that were already reached aren't explored again, so a shared value is walked once per field that reaches it rather than once per path
+				v.visited = true
+			}
 		} else {
-			q.queuedPointers[ptr] = nil
+			q.queuedPointers[key] = joinName(v.Name)
+		}
+		v.ancestors = &pointerChain{key: key, parent: v.ancestors}
+	}
+	return v
+}
+
+// Identifies the value a reference refers to. A pointer to a struct and a pointer to its first field share an address, so the type is part of the key; slices of the same array with different lengths hold different elements.
+type visitKey struct {
+	reflect.Type
+	ptr uintptr
+	len int
+}
+
+// returns the key of the value v refers to, or false if v isn't a reference the traversal follows
+func newVisitKey(v reflect.Value) (visitKey, bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return visitKey{Type: v.Type(), ptr: v.Pointer()}, true
+		}
+	case reflect.Slice, reflect.Map:
+		// empty containers may all share an address
+		if !v.IsNil() && v.Len() != 0 && traversesElements(v.Type()) {
+			return visitKey{Type: v.Type(), ptr: v.Pointer(), len: v.Len()}, true
 		}
 	}
-	// enqueue value
-	q.queue.PushBack(v)
-	return
+	return visitKey{}, false
+}
+
+// returns the key of the memory v is stored in, or false if v can't be addressed. Zero sized values may share their address with other values.
+func newStorageKey(v reflect.Value) (visitKey, bool) {
+	if !v.CanAddr() || v.Type().Size() == 0 {
+		return visitKey{}, false
+	}
+	return visitKey{Type: v.Type(), ptr: v.UnsafeAddr()}, true
+}
+
+// the references followed to reach a node, innermost first
+type pointerChain struct {
+	key    visitKey
+	parent *pointerChain
+}
+
+// true if key was followed to reach the node (i.e. reaching it again would be a cycle)
+func (c *pointerChain) contains(key visitKey) bool {
+	for ; c != nil; c = c.parent {
+		if c.key == key {
+			return true
+		}
+	}
+	return false
 }
 
 func (q *valueQueue) Pop() metaValue {
@@ -146,6 +519,7 @@ func (q *valueQueue) Len() int {
 
 type Field struct {
 	Name   string // qualified field name (e.g. RootType.Field1.Field2)
+	Path   string // field name relative to the root (e.g. Field1.Field2)
 	Value  string // stringified field value
 	Number string // the index in the field tree (e.g. 0.1 for the second field of the first field of the root)
 }
@@ -155,18 +529,10 @@ func newField(v metaValue) Field {
 	for i, num := range v.Number {
 		n[i] = strconv.Itoa(num)
 	}
-	value := ""
-	for _, name := range v.Name[1:] {
-		if name[:1] == strings.ToLower(name)[:1] {
-			value = fmt.Sprintf("unexported field (%v)", v)
-		}
-	}
-	if value == "" {
-		value = fmt.Sprintf("%#v", v.Interface())
-	}
 	return Field{
-		Name:   strings.Join(v.Name, "."),
-		Value:  value,
+		Name:   joinName(v.Name),
+		Path:   joinName(v.Name[1:]),
+		Value:  v.opts.formatValue(v),
 		Number: strings.Join(n, "."),
 	}
 }
@@ -195,3 +561,12 @@ func (a ByFieldOrder) Less(i, j int) bool {
 	}
 	return len(n1) < len(n2)
 }
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
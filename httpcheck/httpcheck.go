@@ -0,0 +1,197 @@
+/*
+Package httpcheck decodes and validates HTTP request bodies with structcheck.
+
+A Decoder reads JSON or form bodies into a value, validates it and, on failure, writes an application/problem+json response listing the failing fields:
+
+	var d httpcheck.Decoder
+
+	func createOrder(w http.ResponseWriter, r *http.Request) {
+	    var order Order
+	    if !d.Decode(w, r, &order) {
+	        return
+	    }
+	    ...
+	}
+*/
+package httpcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Manbeardo/structcheck"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+)
+
+// the body size limit used when Decoder.MaxBodyBytes is 0
+const DefaultMaxBodyBytes = 1 << 20
+
+// the part of a multipart form kept in memory; larger files are stored in temporary files. The body size is still limited by MaxBodyBytes.
+const multipartMemoryBytes = 32 << 20
+
+// Decodes and validates request bodies. The zero value is ready to use.
+type Decoder struct {
+	// maximum accepted body size in bytes. 0 means DefaultMaxBodyBytes; negative means unlimited.
+	MaxBodyBytes int64
+	// options passed to structcheck for every request
+	Options []structcheck.Option
+	// writes the response for a failed request. nil means WriteProblem.
+	WriteError func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// returned when a request's Content-Type is neither JSON nor a form
+type ErrorUnsupportedMediaType struct {
+	ContentType string
+}
+
+func (e ErrorUnsupportedMediaType) Error() string {
+	return fmt.Sprintf("Unsupported content type: %v", e.ContentType)
+}
+
+// Decodes the body of r into v (a non-nil pointer to a struct) according to its Content-Type and validates it. Returns nil if v is valid. w is told to close the connection when the body is too large.
+func (d *Decoder) DecodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	body := io.Reader(r.Body)
+	if limit := d.maxBodyBytes(); limit > 0 {
+		body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if contentType == "" {
+		mediaType, err = "application/json", nil
+	}
+	if err != nil {
+		return ErrorUnsupportedMediaType{ContentType: contentType}
+	}
+	switch mediaType {
+	case "application/json":
+		return structcheck.DecodeAndValidateJSON("body", body, v, d.Options...)
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		r.Body = io.NopCloser(body)
+		if err := r.ParseMultipartForm(multipartMemoryBytes); err != nil && err != http.ErrNotMultipart {
+			return err
+		}
+		presence, err := structcheck.DecodeForm(r.PostForm, v)
+		if err != nil {
+			return err
+		}
+		return structcheck.Validate(v, append(d.Options[:len(d.Options):len(d.Options)], structcheck.WithPresence(presence))...)
+	default:
+		return ErrorUnsupportedMediaType{ContentType: contentType}
+	}
+}
+
+// Decodes and validates the body of r into v like DecodeRequest. On failure it writes an error response and returns false.
+func (d *Decoder) Decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := d.DecodeRequest(w, r, v)
+	if err == nil {
+		return true
+	}
+	if d.WriteError != nil {
+		d.WriteError(w, r, err)
+	} else {
+		WriteProblem(w, r, err)
+	}
+	return false
+}
+
+// Returns middleware that decodes and validates each request body into a new value from newValue before calling the next handler. The value is available to the handler through Value.
+func (d *Decoder) Middleware(newValue func() interface{}) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v := newValue()
+			if !d.Decode(w, r, v) {
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), valueKey{}, v)))
+		})
+	}
+}
+
+type valueKey struct{}
+
+// returns the value decoded by Decoder.Middleware, or nil
+func Value(r *http.Request) interface{} {
+	return r.Context().Value(valueKey{})
+}
+
+func (d *Decoder) maxBodyBytes() int64 {
+	if d.MaxBodyBytes == 0 {
+		return DefaultMaxBodyBytes
+	}
+	return d.MaxBodyBytes
+}
+
+// an RFC 7807 problem details body
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// a field that failed checks
+type FieldError struct {
+	Field  string   `json:"field"`
+	Checks []string `json:"checks"`
+	Line   int      `json:"line,omitempty"`
+	Column int      `json:"column,omitempty"`
+}
+
+// Describes err as a problem. Validation failures get status 422 and a list of field errors; undecodable bodies get 400, oversized bodies 413 and unsupported content types 415. Errors in the validated type or its checks get 500 without details, since they're bugs in the server rather than in the request.
+func NewProblem(err error) Problem {
+	var maxBytes *http.MaxBytesError
+	var unsupported ErrorUnsupportedMediaType
+	switch {
+	case isServerError(err):
+		return Problem{Type: "about:blank", Title: "Internal server error", Status: http.StatusInternalServerError}
+	case errors.As(err, &maxBytes):
+		return Problem{Type: "about:blank", Title: "Request body too large", Status: http.StatusRequestEntityTooLarge, Detail: err.Error()}
+	case errors.As(err, &unsupported):
+		return Problem{Type: "about:blank", Title: "Unsupported media type", Status: http.StatusUnsupportedMediaType, Detail: err.Error()}
+	}
+	switch err := err.(type) {
+	case structcheck.ErrorJSONInvalid:
+		p := Problem{Type: "about:blank", Title: "Validation failed", Status: http.StatusUnprocessableEntity}
+		for _, f := range err.Failures {
+			p.Errors = append(p.Errors, FieldError{Field: f.Path, Checks: f.Checks, Line: f.Position.Line, Column: f.Position.Column})
+		}
+		for _, f := range err.UnknownFields {
+			p.Errors = append(p.Errors, FieldError{Field: f.Path, Checks: []string{"Unknown"}, Line: f.Position.Line, Column: f.Position.Column})
+		}
+		return p
+	case structcheck.ErrorChecksFailed:
+		p := Problem{Type: "about:blank", Title: "Validation failed", Status: http.StatusUnprocessableEntity}
+		fields := make([]structcheck.Field, 0, len(err.Field2Checks))
+		for field := range err.Field2Checks {
+			fields = append(fields, field)
+		}
+		sort.Sort(structcheck.ByFieldOrder(fields))
+		for _, field := range fields {
+			p.Errors = append(p.Errors, FieldError{Field: field.Path, Checks: err.Field2Checks[field]})
+		}
+		return p
+	}
+	return Problem{Type: "about:blank", Title: "Malformed request body", Status: http.StatusBadRequest, Detail: err.Error()}
+}
+
+// true if err is caused by the validated type or the options rather than the request
+func isServerError(err error) bool {
+	var illegalCheck structcheck.ErrorIllegalCheck
+	var invalidKind structcheck.ErrorInvalidKind
+	var unknownFields structcheck.ErrorUnknownFields
+	var nilValue structcheck.ErrorNilValue
+	return errors.As(err, &illegalCheck) || errors.As(err, &invalidKind) || errors.As(err, &unknownFields) || errors.As(err, &nilValue)
+}
+
+// writes err as an application/problem+json response
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := NewProblem(err)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
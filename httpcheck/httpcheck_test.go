@@ -0,0 +1,118 @@
+package httpcheck
+
+import (
+	"encoding/json"
+	"github.com/Manbeardo/structcheck"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testOrder struct {
+	Item     string `json:"item" form:"item" checks:"NotEmpty"`
+	Quantity int    `json:"quantity" form:"quantity" checks:"Positive"`
+	Address  struct {
+		Zip string `json:"zip" form:"zip" checks:"Required"`
+	} `json:"address" form:"address"`
+}
+
+func serve(d *Decoder, contentType, body string) *httptest.ResponseRecorder {
+	handler := d.Middleware(func() interface{} { return new(testOrder) })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Value(r).(*testOrder).Item))
+	}))
+	r := httptest.NewRequest("POST", "/orders", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestDecoder_goodJSON(t *testing.T) {
+	w := serve(&Decoder{}, "application/json", `{"item": "apple", "quantity": 2, "address": {"zip": "12345"}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "apple", w.Body.String())
+}
+
+func TestDecoder_badJSON(t *testing.T) {
+	w := serve(&Decoder{}, "application/json; charset=utf-8", `{"item": "", "quantity": 2}`)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	var p Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	require.Equal(t, []FieldError{
+		{Field: "Item", Checks: []string{"NotEmpty"}, Line: 1, Column: 10},
+		{Field: "Address.Zip", Checks: []string{"Required"}},
+	}, p.Errors)
+}
+
+func TestDecoder_malformedJSON(t *testing.T) {
+	w := serve(&Decoder{}, "application/json", `{"item": `)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDecoder_form(t *testing.T) {
+	w := serve(&Decoder{}, "application/x-www-form-urlencoded", "item=pear&quantity=1&address.zip=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "pear", w.Body.String())
+	w = serve(&Decoder{}, "application/x-www-form-urlencoded", "item=pear&quantity=0")
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestDecoder_unlimitedForm(t *testing.T) {
+	w := serve(&Decoder{MaxBodyBytes: -1}, "application/x-www-form-urlencoded", "item=pear&quantity=1&address.zip=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	body := "--b\r\nContent-Disposition: form-data; name=\"item\"\r\n\r\nfig\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"quantity\"\r\n\r\n3\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"address.zip\"\r\n\r\n1\r\n--b--\r\n"
+	w = serve(&Decoder{MaxBodyBytes: -1}, "multipart/form-data; boundary=b", body)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "fig", w.Body.String())
+}
+
+func TestDecoder_limits(t *testing.T) {
+	w := serve(&Decoder{MaxBodyBytes: 8}, "application/json", `{"item": "apple"}`)
+	require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	w = serve(&Decoder{}, "text/plain", "apple")
+	require.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestDecoder_limitClosesConnection(t *testing.T) {
+	server := httptest.NewServer((&Decoder{MaxBodyBytes: 8}).Middleware(func() interface{} { return new(testOrder) })(http.NotFoundHandler()))
+	defer server.Close()
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"item": "apple"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	require.True(t, resp.Close)
+}
+
+func TestDecoder_serverErrors(t *testing.T) {
+	w := serve(&Decoder{Options: []structcheck.Option{structcheck.WithFieldMask("Missing")}}, "application/json", `{}`)
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	var p Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	require.Equal(t, Problem{Type: "about:blank", Title: "Internal server error", Status: http.StatusInternalServerError}, p)
+
+	var d Decoder
+	var bad struct {
+		Name string `json:"name" checks:"MinLen(two)"`
+	}
+	err := d.DecodeRequest(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(`{"name": "x"}`)), &bad)
+	require.IsType(t, structcheck.ErrorIllegalCheck{}, err)
+	require.Equal(t, http.StatusInternalServerError, NewProblem(err).Status)
+	require.Empty(t, NewProblem(err).Detail)
+	p = NewProblem(structcheck.ErrorInvalidKind{Type: reflect.TypeOf(0)})
+	require.Equal(t, http.StatusInternalServerError, p.Status)
+	require.Empty(t, p.Detail)
+}
+
+func TestDecoder_customWriteError(t *testing.T) {
+	d := &Decoder{WriteError: func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusTeapot)
+	}}
+	w := serve(d, "application/json", `{}`)
+	require.Equal(t, http.StatusTeapot, w.Code)
+}
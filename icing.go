@@ -3,6 +3,7 @@ package structcheck
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -10,47 +11,106 @@ import (
 func BuildFixedCheckFinder(checkNames []string, checkSet map[string]Check) (CheckFinder, error) {
 	checks := make([]Check, len(checkNames))
 	for i, checkName := range checkNames {
-		check, ok := checkSet[checkName]
-		if !ok {
+		check, err := lookupCheck(checkName, checkSet)
+		if err != nil {
+			return nil, err
+		}
+		if check == nil {
 			return nil, fmt.Errorf("No check found with name: %v", checkName)
 		}
 		checks[i] = check
 	}
+	checkSet = copyCheckSet(checkSet)
 	return func(v metaValue) ([]Check, []string, error) {
+		if v.lookup != nil {
+			return lookupChecks(v, checkSet)
+		}
 		return checks, checkNames, nil
 	}, nil
 }
 
-// Builds a CheckFinder that runs the named checks on the named fields. checkSet and field2checks are copied and verified at build-time
+// Builds a CheckFinder that runs the named checks on the named fields. checkSet and field2checks are copied and verified at build-time.
+//
+// Field names are dotted paths relative to the root struct and may contain glob-style segments: * matches any single field, ** any number of segments (including none), [*] any element index or map key and (TypeName) any single field whose value is of that type (or a pointer to it). For example:
+//
+//	Address.*           every field of Address
+//	**.ID               ID at any depth
+//	Items[*].ID         ID of every element of Items
+//	**.(Address).Zip    Zip of every Address, at any depth
+//
+// When several patterns match the same field, only the most specific one applies: the leftmost segment that differs decides (literal > type > * or [*] > **), then longer patterns beat shorter ones.
 func BuildStringyCheckFinder(field2checks map[string][]string, checkSet map[string]Check) (CheckFinder, error) {
-	f2c := make(map[string][]string, len(field2checks))
-	cs := make(map[string]Check, len(checkSet))
-	for k, v := range field2checks {
-		f2c[k] = v
-	}
-	for k, v := range checkSet {
-		cs[k] = v
+	patterns, f2c, err := compileStringyPatterns(field2checks, checkSet)
+	if err != nil {
+		return nil, err
 	}
-
-	for _, checkNames := range f2c {
-		for _, checkName := range checkNames {
-			if _, ok := cs[checkName]; !ok {
-				return nil, fmt.Errorf("No check found with name: %v", checkName)
+	checkSet = copyCheckSet(checkSet)
+	return func(v metaValue) ([]Check, []string, error) {
+		if v.lookup != nil {
+			return lookupChecks(v, checkSet)
+		}
+		for _, pattern := range patterns {
+			if pattern.Match(v.Name[1:], v.Types[1:]) {
+				return f2c.checks[pattern.text], f2c.names[pattern.text], nil
 			}
 		}
+		return []Check{}, []string{}, nil
+	}, nil
+}
+
+// Builds a CheckFinder like BuildStringyCheckFinder, additionally verifying that each pattern matches at least one field reachable from type t.
+func BuildTypedStringyCheckFinder(t reflect.Type, field2checks map[string][]string, checkSet map[string]Check) (CheckFinder, error) {
+	patterns, _, err := compileStringyPatterns(field2checks, checkSet)
+	if err != nil {
+		return nil, err
 	}
-	return func(v metaValue) ([]Check, []string, error) {
-		name := strings.Join(v.Name[1:], ".")
-		if checkNames, ok := f2c[name]; ok {
-			checks := make([]Check, len(checkNames))
-			for i, checkName := range checkNames {
-				checks[i] = cs[checkName]
+	unmatched := []string{}
+	for _, pattern := range patterns {
+		if !pattern.MatchesType(t) {
+			unmatched = append(unmatched, pattern.text)
+		}
+	}
+	if len(unmatched) != 0 {
+		sort.Strings(unmatched)
+		return nil, fmt.Errorf("Pattern(s) %v do not match any field in %v", unmatched, t)
+	}
+	return BuildStringyCheckFinder(field2checks, checkSet)
+}
+
+// resolved checks for each pattern of a stringy finder
+type stringyChecks struct {
+	checks map[string][]Check
+	names  map[string][]string
+}
+
+func compileStringyPatterns(field2checks map[string][]string, checkSet map[string]Check) ([]pathPattern, stringyChecks, error) {
+	f2c := stringyChecks{
+		checks: make(map[string][]Check, len(field2checks)),
+		names:  make(map[string][]string, len(field2checks)),
+	}
+	patterns := make([]pathPattern, 0, len(field2checks))
+	for field, checkNames := range field2checks {
+		pattern, err := parsePathPattern(field)
+		if err != nil {
+			return nil, f2c, err
+		}
+		checks := make([]Check, len(checkNames))
+		for i, checkName := range checkNames {
+			check, err := lookupCheck(checkName, checkSet)
+			if err != nil {
+				return nil, f2c, err
+			}
+			if check == nil {
+				return nil, f2c, fmt.Errorf("No check found with name: %v", checkName)
 			}
-			return checks, checkNames, nil
-		} else {
-			return []Check{}, []string{}, nil
+			checks[i] = check
 		}
-	}, nil
+		patterns = append(patterns, pattern)
+		f2c.checks[field] = checks
+		f2c.names[field] = append([]string{}, checkNames...)
+	}
+	sortPatterns(patterns)
+	return patterns, f2c, nil
 }
 
 func CheckFieldExists(i interface{}, fieldName string) bool {
@@ -71,13 +131,23 @@ func CheckFieldExists(i interface{}, fieldName string) bool {
 				v = v.Elem()
 			}
 		case reflect.Struct:
-			v = v.FieldByName(fields[0])
-			if v.Kind() == reflect.Invalid {
+			f, ok := v.Type().FieldByName(fields[0])
+			if !ok {
 				return false
-			} else {
-				// pop
-				fields = fields[1:]
 			}
+			// promoted fields may be reached through nil embedded pointers
+			for _, i := range f.Index {
+				for v.Kind() == reflect.Ptr {
+					if v.IsNil() {
+						v = reflect.Zero(v.Type().Elem())
+					} else {
+						v = v.Elem()
+					}
+				}
+				v = v.Field(i)
+			}
+			// pop
+			fields = fields[1:]
 		default:
 			return false
 		}
@@ -115,11 +185,12 @@ func CheckFieldsNotNil(i interface{}, fieldNames []string) error {
 	}
 	field2checks := make(map[string][]string, len(fieldNames))
 	checks := []string{"NotNil"}
+	t := reflect.TypeOf(i)
 	for _, name := range fieldNames {
-		exploded := strings.Split(name, ".")
-		for i := 0; i < len(exploded); i++ {
-			subname := strings.Join(exploded[:len(exploded)-i], ".")
-			field2checks[subname] = checks
+		// promoted fields may be named with or without their embedded structs
+		paths, _ := resolveFieldPaths(t, name, false)
+		for _, path := range paths {
+			field2checks[joinName(path)] = checks
 		}
 	}
 
@@ -2,6 +2,8 @@ package structcheck
 
 import (
 	"github.com/stretchr/testify/require"
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -55,3 +57,117 @@ func TestCheckFieldNotNil_badNestedNilField(t *testing.T) {
 func TestCheckFieldNotNil_badFieldNestedUnderNil(t *testing.T) {
 	require.Error(t, CheckFieldsNotNil(pointyTestStruct{}, []string{"b.a"}))
 }
+
+type patternTestAddress struct {
+	Zip    string
+	Street string
+}
+
+type patternTestStruct struct {
+	ID    *int
+	Home  patternTestAddress
+	Work  *patternTestAddress
+	Items []patternTestItem
+	ByKey map[string]patternTestItem
+}
+
+type patternTestItem struct {
+	ID   *int
+	Name string
+}
+
+func failedFieldNames(t *testing.T, err error) []string {
+	require.IsType(t, ErrorChecksFailed{}, err)
+	names := []string{}
+	for field := range err.(ErrorChecksFailed).Field2Checks {
+		names = append(names, field.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestBuildStringyCheckFinder_wildcards(t *testing.T) {
+	finder, err := BuildStringyCheckFinder(map[string][]string{
+		"**.ID":      {"NotNil"},
+		"Items[*]":   {"Nilable"},
+		"ByKey[*].*": {"NotEmpty"},
+		"*.Zip":      {"NotEmpty"},
+	}, DefaultChecks)
+	require.NoError(t, err)
+	err = CustomValidate(patternTestStruct{
+		Items: []patternTestItem{{}},
+		ByKey: map[string]patternTestItem{"a": {}},
+	}, finder)
+	// ByKey[a].ID is claimed by the more specific ByKey[*].* pattern
+	require.Equal(t, []string{
+		"patternTestStruct.ByKey[a].Name",
+		"patternTestStruct.Home.Zip",
+		"patternTestStruct.ID",
+		"patternTestStruct.Items[0]",
+		"patternTestStruct.Items[0].ID",
+	}, failedFieldNames(t, err))
+}
+
+func TestBuildStringyCheckFinder_typeSelector(t *testing.T) {
+	finder, err := BuildStringyCheckFinder(map[string][]string{
+		"**.(patternTestAddress).Zip": {"NotEmpty"},
+	}, DefaultChecks)
+	require.NoError(t, err)
+	err = CustomValidate(patternTestStruct{Work: &patternTestAddress{}}, finder)
+	require.Equal(t, []string{
+		"patternTestStruct.Home.Zip",
+		"patternTestStruct.Work.Zip",
+	}, failedFieldNames(t, err))
+}
+
+func TestBuildStringyCheckFinder_precedence(t *testing.T) {
+	finder, err := BuildStringyCheckFinder(map[string][]string{
+		"**":       {"Empty"},
+		"Home.*":   {"NotEmpty"},
+		"*.Street": {"Empty"},
+		"Home.Zip": {"Empty"},
+	}, DefaultChecks)
+	require.NoError(t, err)
+	err = CustomValidate(patternTestStruct{
+		Home: patternTestAddress{Zip: "z", Street: "s"},
+	}, finder)
+	require.Equal(t, []string{
+		"patternTestStruct.Home.Zip",
+	}, failedFieldNames(t, err))
+}
+
+func TestBuildTypedStringyCheckFinder_good(t *testing.T) {
+	_, err := BuildTypedStringyCheckFinder(reflect.TypeOf(patternTestStruct{}), map[string][]string{
+		"ID":                          {"NotNil"},
+		"Items[*].Name":               {"NotEmpty"},
+		"Items[3].Name":               {"NotEmpty"},
+		"**.(patternTestAddress).Zip": {"NotEmpty"},
+	}, DefaultChecks)
+	require.NoError(t, err)
+}
+
+func TestBuildTypedStringyCheckFinder_bad(t *testing.T) {
+	_, err := BuildTypedStringyCheckFinder(reflect.TypeOf(patternTestStruct{}), map[string][]string{
+		"Items.Name":     {"NotEmpty"},
+		"**.Postcode":    {"NotEmpty"},
+		"(int).Anything": {"NotEmpty"},
+	}, DefaultChecks)
+	require.Error(t, err)
+}
+
+func TestBuildStringyCheckFinder_badPattern(t *testing.T) {
+	_, err := BuildStringyCheckFinder(map[string][]string{"Items.Na*": {"NotEmpty"}}, DefaultChecks)
+	require.Error(t, err)
+}
+
+func TestCheckFieldsNotNil_embedded(t *testing.T) {
+	record := embedRecord{embedBase: &embedBase{}}
+	require.True(t, CheckFieldExists(record, "Owner"))
+	require.True(t, CheckFieldExists(record, "embedBase.Owner"))
+	// promoted fields can be named with or without their embedded struct
+	require.Error(t, CheckFieldsNotNil(record, []string{"Owner"}))
+	require.Error(t, CheckFieldsNotNil(record, []string{"embedBase.Owner"}))
+	require.Error(t, CheckFieldsNotNil(embedRecord{}, []string{"Owner"}))
+	record.Owner = &embedOwner{}
+	require.NoError(t, CheckFieldsNotNil(record, []string{"Owner", "embedBase.Owner"}))
+}
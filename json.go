@@ -0,0 +1,353 @@
+package structcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Records which fields were present in a decoded document and where their values start. Paths are relative to the root struct, as used by BuildStringyCheckFinder (e.g. NestedObject.B or Items[3].ID).
+type Presence struct {
+	offsets       map[string]int64
+	qualified     map[string]string // paths naming embedded structs (see WithEmbeddedTypeNames), where they differ
+	lineStarts    []int64
+	unknownFields []JSONUnknownField
+}
+
+func newPresence(data []byte) *Presence {
+	lineStarts := []int64{0}
+	for i, b := range data {
+		if b == '\n' {
+			lineStarts = append(lineStarts, int64(i+1))
+		}
+	}
+	return &Presence{
+		offsets:    make(map[string]int64),
+		qualified:  make(map[string]string),
+		lineStarts: lineStarts,
+	}
+}
+
+// true if the field at path was present in the document
+func (p *Presence) Has(path string) bool {
+	_, ok := p.offsets[p.unqualified(path)]
+	return ok
+}
+
+// translates a path that names embedded structs to the default naming
+func (p *Presence) unqualified(path string) string {
+	if promoted, ok := p.qualified[path]; ok {
+		return promoted
+	}
+	return path
+}
+
+// records that the field at path (named qualifiedPath under WithEmbeddedTypeNames) starts at offset
+func (p *Presence) record(path []string, qualifiedPath []string, offset int64) {
+	name := joinName(path)
+	p.offsets[name] = offset
+	if qualifiedName := joinName(qualifiedPath); qualifiedName != name {
+		p.qualified[qualifiedName] = name
+	}
+}
+
+// returns the paths of all present fields in sorted order. Useful as a field mask for partial updates.
+func (p *Presence) Paths() []string {
+	paths := make([]string, 0, len(p.offsets))
+	for path := range p.offsets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// returns the position of the value of the field at path, if it was present in the document
+func (p *Presence) Position(path string) (Position, bool) {
+	offset, ok := p.offsets[p.unqualified(path)]
+	if !ok {
+		return Position{}, false
+	}
+	return p.position(offset), true
+}
+
+// returns the object keys that didn't match any field, in document order
+func (p *Presence) UnknownFields() []JSONUnknownField {
+	return p.unknownFields
+}
+
+func (p *Presence) position(offset int64) Position {
+	line := sort.Search(len(p.lineStarts), func(i int) bool {
+		return p.lineStarts[i] > offset
+	})
+	return Position{
+		Offset: offset,
+		Line:   line,
+		Column: int(offset-p.lineStarts[line-1]) + 1,
+	}
+}
+
+// a location in a source document. Lines and columns (in bytes) start at 1.
+type Position struct {
+	Offset int64
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// an object key that doesn't correspond to any field
+type JSONUnknownField struct {
+	Path     string // path of the key (e.g. NestedObject.Typo)
+	Position Position
+}
+
+// Decodes a single JSON document from r into v (as json.Unmarshal would) and records which fields of v were present in the document.
+func DecodeJSON(r io.Reader, v interface{}) (*Presence, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	w := jsonWalker{
+		data: data,
+		dec:  json.NewDecoder(bytes.NewReader(data)),
+		p:    newPresence(data),
+	}
+	if err := w.walk(nil, nil, structScope{t: reflect.TypeOf(v)}, reflect.TypeOf(v)); err != nil {
+		return nil, err
+	}
+	return w.p, nil
+}
+
+// Decodes a JSON document from r into v, then validates it with presence information (see WithPresence). Failures are reported with their positions in the document, and unknown object keys are reported too. source names the document in error messages.
+func DecodeAndValidateJSON(source string, r io.Reader, v interface{}, opts ...Option) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	p, err := DecodeJSON(bytes.NewReader(data), v)
+	if err != nil {
+		return newErrorJSONDecode(source, data, err)
+	}
+	err = CustomValidate(v, BuildTagCheckFinder(DefaultChecks), append(opts[:len(opts):len(opts)], WithPresence(p))...)
+	checksFailed, ok := err.(ErrorChecksFailed)
+	if err != nil && !ok {
+		return err
+	}
+	if len(checksFailed.Field2Checks) == 0 && len(p.unknownFields) == 0 {
+		return nil
+	}
+	fields := make([]Field, 0, len(checksFailed.Field2Checks))
+	for field := range checksFailed.Field2Checks {
+		fields = append(fields, field)
+	}
+	sort.Sort(ByFieldOrder(fields))
+	failures := make([]JSONFailure, len(fields))
+	for i, field := range fields {
+		failures[i] = JSONFailure{
+			Field:  field,
+			Checks: checksFailed.Field2Checks[field],
+		}
+		failures[i].Position, failures[i].Present = p.Position(field.Path)
+	}
+	return ErrorJSONInvalid{
+		Source:        source,
+		Failures:      failures,
+		UnknownFields: p.unknownFields,
+	}
+}
+
+// walks a JSON token stream alongside the Go type it decodes into
+type jsonWalker struct {
+	data []byte
+	dec  *json.Decoder
+	p    *Presence
+}
+
+// returns the offset at which the next key or value starts
+func (w *jsonWalker) offset() int64 {
+	offset := w.dec.InputOffset()
+	for offset < int64(len(w.data)) && strings.IndexByte(" \t\r\n,:", w.data[offset]) != -1 {
+		offset++
+	}
+	return offset
+}
+
+// consumes the next value, recording its fields if t is not nil. qpath is path under WithEmbeddedTypeNames, and scope names the fields of structs.
+func (w *jsonWalker) walk(path []string, qpath []string, scope structScope, t reflect.Type) error {
+	tok, err := w.dec.Token()
+	if err != nil {
+		return err
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch tok {
+	case json.Delim('{'):
+		var fields jsonFieldList
+		if t != nil && t.Kind() == reflect.Struct {
+			fields = jsonFields(t)
+		}
+		for w.dec.More() {
+			keyOffset := w.offset()
+			key, err := w.dec.Token()
+			if err != nil {
+				return err
+			}
+			var childPath, childQPath []string
+			var childScope structScope
+			var childType reflect.Type
+			switch {
+			case t == nil:
+			case t.Kind() == reflect.Struct:
+				if field, ok := fields.lookup(key.(string)); ok {
+					// the field may be promoted through embedded structs without JSON names
+					childScope = scope
+					for _, i := range field.index {
+						childPath, childQPath, childScope = childScope.field(i)
+					}
+					childType = t.FieldByIndex(field.index).Type
+				} else {
+					w.p.unknownFields = append(w.p.unknownFields, JSONUnknownField{
+						Path:     joinName(appendPath(path, key.(string))),
+						Position: w.p.position(keyOffset),
+					})
+				}
+			case t.Kind() == reflect.Map:
+				childPath = appendPath(path, fmt.Sprintf("[%v]", key))
+				childQPath = appendPath(qpath, fmt.Sprintf("[%v]", key))
+				childType = t.Elem()
+				childScope = structScope{t: childType, path: childPath, qpath: childQPath}
+			}
+			if err := w.walkChild(childPath, childQPath, childScope, childType); err != nil {
+				return err
+			}
+		}
+		_, err = w.dec.Token()
+	case json.Delim('['):
+		for i := 0; w.dec.More(); i++ {
+			var childPath, childQPath []string
+			var childScope structScope
+			var childType reflect.Type
+			if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+				childPath = appendPath(path, fmt.Sprintf("[%d]", i))
+				childQPath = appendPath(qpath, fmt.Sprintf("[%d]", i))
+				childType = t.Elem()
+				childScope = structScope{t: childType, path: childPath, qpath: childQPath}
+			}
+			if err := w.walkChild(childPath, childQPath, childScope, childType); err != nil {
+				return err
+			}
+		}
+		_, err = w.dec.Token()
+	}
+	return err
+}
+
+func (w *jsonWalker) walkChild(path []string, qpath []string, scope structScope, t reflect.Type) error {
+	if t != nil {
+		w.p.record(path, qpath, w.offset())
+	}
+	return w.walk(path, qpath, scope, t)
+}
+
+// returns a copy of path extended by segments
+func appendPath(path []string, segments ...string) []string {
+	extended := make([]string, len(path), len(path)+len(segments))
+	copy(extended, path)
+	return append(extended, segments...)
+}
+
+// a struct field as seen by encoding/json
+type jsonField struct {
+	name  string
+	index []int
+}
+
+type jsonFieldList []jsonField
+
+// finds the field a JSON object key decodes into, preferring an exact match over a case-insensitive one like encoding/json
+func (fields jsonFieldList) lookup(key string) (jsonField, bool) {
+	for _, field := range fields {
+		if field.name == key {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.name, key) {
+			return field, true
+		}
+	}
+	return jsonField{}, false
+}
+
+// lists the fields of struct type t that encoding/json decodes into, including fields promoted from untagged embedded structs. Shallower fields hide deeper ones with the same name.
+func jsonFields(t reflect.Type) jsonFieldList {
+	fields := jsonFieldList{}
+	seen := make(map[string]bool)
+	type level struct {
+		reflect.Type
+		index []int
+	}
+	current := []level{{Type: t}}
+	visited := make(map[reflect.Type]bool)
+	for len(current) != 0 {
+		next := []level{}
+		names := make(map[string]int)
+		found := jsonFieldList{}
+		for _, l := range current {
+			if visited[l.Type] {
+				continue
+			}
+			visited[l.Type] = true
+			for i := 0; i < l.NumField(); i++ {
+				f := l.Field(i)
+				index := make([]int, len(l.index), len(l.index)+1)
+				copy(index, l.index)
+				index = append(index, i)
+				tag := f.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name := strings.Split(tag, ",")[0]
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					next = append(next, level{Type: ft, index: index})
+					continue
+				}
+				// like encoding/json, embedded structs of unexported types are decoded when they have a JSON name
+				if f.PkgPath != "" && !(f.Anonymous && ft.Kind() == reflect.Struct) {
+					continue
+				}
+				if name == "" {
+					name = f.Name
+				}
+				names[name]++
+				found = append(found, jsonField{name: name, index: index})
+			}
+		}
+		for _, field := range found {
+			// ambiguous fields at the same depth are ignored, like encoding/json does for untagged duplicates
+			if !seen[field.name] && names[field.name] == 1 {
+				fields = append(fields, field)
+			}
+		}
+		for name := range names {
+			seen[name] = true
+		}
+		current = next
+	}
+	return fields
+}
@@ -0,0 +1,152 @@
+package structcheck
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+type jsonTestEmbedded struct {
+	Created string `json:"created"`
+}
+
+type jsonTestStruct struct {
+	jsonTestEmbedded
+	ID       string `json:"id" checks:"Forbidden"`
+	Count    int    `json:"count" checks:"Required"`
+	Enabled  bool   `checks:"Required"`
+	Children []struct {
+		Name string `json:"name" checks:"Required"`
+	} `json:"children"`
+	Labels  map[string]string `json:"labels"`
+	Ignored string            `json:"-"`
+}
+
+func TestDecodeJSON_presence(t *testing.T) {
+	var o jsonTestStruct
+	p, err := DecodeJSON(strings.NewReader(`{
+		"count": 0,
+		"enabled": false,
+		"created": "now",
+		"children": [{"name": ""}, {}],
+		"labels": {"a": "b"},
+		"Ignored": "x",
+		"unknown": 1
+	}`), &o)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"Children",
+		"Children[0]",
+		"Children[0].Name",
+		"Children[1]",
+		"Count",
+		"Created",
+		"Enabled",
+		"Labels",
+		"Labels[a]",
+	}, p.Paths())
+	require.True(t, p.Has("jsonTestEmbedded.Created"))
+	err = Validate(o, WithPresence(p))
+	require.Equal(t, map[string][]string{
+		"jsonTestStruct.Children[1].Name": {"Required"},
+	}, failuresByName(t, err))
+}
+
+func TestDecodeJSON_forbidden(t *testing.T) {
+	var o jsonTestStruct
+	p, err := DecodeJSON(strings.NewReader(`{"id": "", "count": 1, "Enabled": true}`), &o)
+	require.NoError(t, err)
+	err = Validate(o, WithPresence(p))
+	require.Equal(t, map[string][]string{
+		"jsonTestStruct.ID": {"Forbidden"},
+	}, failuresByName(t, err))
+}
+
+func TestRequiredWithoutPresence(t *testing.T) {
+	err := Validate(jsonTestStruct{ID: "x", Enabled: true})
+	require.Equal(t, map[string][]string{
+		"jsonTestStruct.ID":    {"Forbidden"},
+		"jsonTestStruct.Count": {"Required"},
+	}, failuresByName(t, err))
+}
+
+type jsonTestConfig struct {
+	NestedObject struct {
+		A *int `checks:"NotNil"`
+		B *int `checks:"NotNil"`
+		C int  `checks:"Positive"`
+	}
+}
+
+func TestDecodeAndValidateJSON(t *testing.T) {
+	var o jsonTestConfig
+	err := DecodeAndValidateJSON("file.json", strings.NewReader(`{
+  "NestedObject": {
+    "A": 1,
+    "C":  -1,
+    "Typo": 2
+  }
+}`), &o)
+	require.IsType(t, ErrorJSONInvalid{}, err)
+	e := err.(ErrorJSONInvalid)
+	require.Len(t, e.Failures, 2)
+	require.False(t, e.Failures[0].Present)
+	require.Equal(t, Position{Offset: 44, Line: 4, Column: 11}, e.Failures[1].Position)
+	require.Equal(t, "file.json: NestedObject.B: NotNil (absent)\n"+
+		"file.json:4:11: NestedObject.C: Positive\n"+
+		"file.json:5:5: NestedObject.Typo: unknown field", err.Error())
+}
+
+func TestDecodeAndValidateJSON_syntaxError(t *testing.T) {
+	var o jsonTestConfig
+	err := DecodeAndValidateJSON("file.json", strings.NewReader("{\n  \"NestedObject\": {,}\n}"), &o)
+	require.IsType(t, ErrorJSONDecode{}, err)
+	require.Equal(t, 2, err.(ErrorJSONDecode).Position.Line)
+}
+
+type jsonTestEmbeddedRequired struct {
+	Created string `json:"created" checks:"Required"`
+}
+
+type jsonTestRequiredEmbedded struct {
+	jsonTestEmbeddedRequired
+	Count int `json:"count" checks:"Required"`
+}
+
+func TestDecodeJSON_embeddedTypeNames(t *testing.T) {
+	var o jsonTestRequiredEmbedded
+	p, err := DecodeJSON(strings.NewReader(`{"count": 1, "created": "now"}`), &o)
+	require.NoError(t, err)
+	require.True(t, p.Has("Created"))
+	require.True(t, p.Has("jsonTestEmbeddedRequired.Created"))
+	require.NoError(t, Validate(o, WithPresence(p)))
+	require.NoError(t, Validate(o, WithPresence(p), WithEmbeddedTypeNames()))
+	p, err = DecodeJSON(strings.NewReader(`{"count": 1}`), &o)
+	require.NoError(t, err)
+	err = Validate(o, WithPresence(p), WithEmbeddedTypeNames())
+	require.Equal(t, map[string][]string{
+		"jsonTestRequiredEmbedded.jsonTestEmbeddedRequired.Created": {"Required"},
+	}, failuresByName(t, err))
+}
+
+type jsonTestTaggedEmbedded struct {
+	jsonTestEmbeddedRequired `json:"base"`
+	Count                    int `json:"count" checks:"Required"`
+}
+
+func TestDecodeJSON_taggedEmbedded(t *testing.T) {
+	var o jsonTestTaggedEmbedded
+	p, err := DecodeJSON(strings.NewReader(`{"count": 1, "base": {"created": "now"}}`), &o)
+	require.NoError(t, err)
+	// the embedded struct's fields are promoted even though JSON nests them
+	require.True(t, p.Has("Created"))
+	require.True(t, p.Has("jsonTestEmbeddedRequired.Created"))
+	require.NoError(t, Validate(o, WithPresence(p)))
+	require.NoError(t, Validate(o, WithPresence(p), WithEmbeddedTypeNames()))
+	p, err = DecodeJSON(strings.NewReader(`{"count": 1, "base": {}}`), &o)
+	require.NoError(t, err)
+	err = Validate(o, WithPresence(p))
+	require.Equal(t, map[string][]string{
+		"jsonTestTaggedEmbedded.Created": {"Required"},
+	}, failuresByName(t, err))
+}
@@ -0,0 +1,169 @@
+package structcheck
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// User-facing message templates for failed checks, keyed by check name. Templates use text/template syntax and are executed with a MessageData. Other keys name messages that checkmsg tags can refer to (see MessageTag). WithMessages adds templates for a single validation, e.g. for the checks of a custom checkSet.
+var DefaultMessages = map[string]string{
+	"NotNil":    "is required",
+	"Nil":       "must not be set",
+	"Positive":  "must be positive",
+	"Negative":  "must be negative",
+	"NoSign":    "must be zero",
+	"NotEmpty":  "must not be empty",
+	"Empty":     "must be empty",
+	"NotZero":   "is required",
+	"Zero":      "must not be set",
+	"Required":  "is required",
+	"Forbidden": "must not be supplied",
+	"Nilable":   "has the wrong type",
+	"Numeric":   "must be a number",
+	"Container": "must be a list",
+	"Exclusive": "must not be shared",
+	"MinLen":    "must have a length of at least {{.Param}}",
+	"MaxLen":    "must have a length of at most {{.Param}}",
+}
+
+// What message templates can refer to, e.g. `checkmsg:"must be at least {{.Param}} characters long"`
+type MessageData struct {
+	Field string // the field's path relative to the root (e.g. Address.Zip)
+	Value string // the field's value, as in Field.Value
+	Check string // the name of the failed check, without its parameter or ptr:/elem: prefix (e.g. MinLen)
+	Param string // the check's parameter (e.g. 5 for MinLen(5)), if any
+}
+
+// The tag that overrides the messages of a field's failed checks. It holds either one message for all checks, or Check=message entries separated by semicolons (e.g. `checkmsg:"NotEmpty=is required;MinLen=is too short"`). A message may also be a key of WithMessages or DefaultMessages.
+const MessageTag = "checkmsg"
+
+var messageEntry = regexp.MustCompile(`^((?:ptr:|elem:)?[A-Za-z][A-Za-z0-9]*(?:\([^)]*\))?)=(.*)$`)
+
+// a parsed checkmsg tag
+type messageTag struct {
+	all     *tagMessage            // nil if the tag doesn't have a message for all checks
+	byCheck map[string]*tagMessage // keyed by check name, with or without a parameter
+}
+
+// a message from a checkmsg tag. Keys of messages are resolved when failures are rendered, so the tag can be cached.
+type tagMessage struct {
+	text string
+	tmpl *template.Template // text parsed as a template, used unless text is a key
+}
+
+var messageTags sync.Map // tag -> messageTag, shared by concurrent traversals
+
+func parseMessageTag(tag string) (messageTag, error) {
+	if cached, ok := messageTags.Load(tag); ok {
+		return cached.(messageTag), nil
+	}
+	parsed := messageTag{byCheck: make(map[string]*tagMessage)}
+	for _, entry := range strings.Split(tag, ";") {
+		if entry == "" {
+			continue
+		}
+		check, text := "", entry
+		if match := messageEntry.FindStringSubmatch(entry); match != nil {
+			check, text = match[1], match[2]
+		}
+		tmpl, err := template.New(check).Parse(text)
+		if err != nil {
+			return messageTag{}, err
+		}
+		if check == "" {
+			parsed.all = &tagMessage{text: text, tmpl: tmpl}
+		} else {
+			parsed.byCheck[check] = &tagMessage{text: text, tmpl: tmpl}
+		}
+	}
+	messageTags.Store(tag, parsed)
+	return parsed, nil
+}
+
+var messageTemplates sync.Map // text -> *template.Template, for the templates of WithMessages and DefaultMessages
+
+// parses a template from WithMessages or DefaultMessages
+func parseMessage(text string) (*template.Template, error) {
+	if cached, ok := messageTemplates.Load(text); ok {
+		return cached.(*template.Template), nil
+	}
+	tmpl, err := template.New("message").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	messageTemplates.Store(text, tmpl)
+	return tmpl, nil
+}
+
+// returns the tag's message for a failed check, if any
+func (t messageTag) lookup(checkName, check string) *tagMessage {
+	if m, ok := t.byCheck[checkName]; ok {
+		return m
+	}
+	if _, unprefixed := splitCheckLevel(checkName); unprefixed != checkName {
+		if m, ok := t.byCheck[unprefixed]; ok {
+			return m
+		}
+	}
+	if m, ok := t.byCheck[check]; ok {
+		return m
+	}
+	return t.all
+}
+
+// returns the template for a failed check, falling back to WithMessages and DefaultMessages. Returns nil if none of them has a message for the check.
+func (t messageTag) template(o *options, checkName, check string) (*template.Template, error) {
+	if m := t.lookup(checkName, check); m != nil {
+		if text, ok := o.message(m.text); ok {
+			return parseMessage(text)
+		}
+		return m.tmpl, nil
+	}
+	if text, ok := o.message(check); ok {
+		return parseMessage(text)
+	}
+	return nil, nil
+}
+
+// splits a Name(param) check name into its name and parameter
+func splitCheckParam(checkName string) (string, string) {
+	open := strings.Index(checkName, "(")
+	if open > 0 && strings.HasSuffix(checkName, ")") {
+		return checkName[:open], checkName[open+1 : len(checkName)-1]
+	}
+	return checkName, ""
+}
+
+// renders the messages for v's failed checks
+func failureMessages(v metaValue, field Field, checkNames []string) ([]string, error) {
+	tag := messageTag{}
+	if v.tag != nil {
+		var err error
+		if tag, err = parseMessageTag(v.tag.Get(MessageTag)); err != nil {
+			return nil, ErrorIllegalCheck{value: v, Reason: fmt.Sprintf("invalid %v tag: %v", MessageTag, err)}
+		}
+	}
+	messages := make([]string, len(checkNames))
+	for i, checkName := range checkNames {
+		_, unprefixed := splitCheckLevel(checkName)
+		check, param := splitCheckParam(unprefixed)
+		tmpl, err := tag.template(v.opts, checkName, check)
+		if err == nil && tmpl == nil {
+			messages[i] = checkName
+			continue
+		}
+		buf := new(bytes.Buffer)
+		if err == nil {
+			err = tmpl.Execute(buf, MessageData{Field: field.Path, Value: field.Value, Check: check, Param: param})
+		}
+		if err != nil {
+			return nil, ErrorIllegalCheck{value: v, Reason: fmt.Sprintf("invalid message for %v: %v", checkName, err)}
+		}
+		messages[i] = buf.String()
+	}
+	return messages, nil
+}
@@ -0,0 +1,95 @@
+package structcheck
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type messageTestSignup struct {
+	Name     string `checks:"NotEmpty,MinLen(2)"`
+	Username string `checks:"NotEmpty,MinLen(3)" checkmsg:"NotEmpty=pick a username;MinLen(3)={{.Field}} needs {{.Param}}+ characters, not {{.Value}}"`
+	Email    string `checks:"NotEmpty" checkmsg:"email.missing"`
+	Age      int    `checks:"Positive" checkmsg:"must be a real age"`
+	Password string `checks:"sensitive,MinLen(8)" checkmsg:"{{.Check}}: {{.Value}}"`
+}
+
+var messageTestMessages = map[string]string{
+	"email.missing": "we need an email address",
+}
+
+func TestMessages(t *testing.T) {
+	err := Validate(messageTestSignup{Username: "al", Password: "hunter2"}, WithMessages(messageTestMessages))
+	require.IsType(t, ErrorChecksFailed{}, err)
+	assert.Equal(t, []string{
+		"Name: must not be empty",
+		"Name: must have a length of at least 2",
+		"Username: Username needs 3+ characters, not \"al\"",
+		"Email: we need an email address",
+		"Age: must be a real age",
+		"Password: MinLen: " + RedactedValue,
+	}, err.(ErrorChecksFailed).UserMessages())
+}
+
+type messageTestCustom struct {
+	Code string `checks:"Upper,NotEmpty"`
+	Note string `checks:"NotEmpty" checkmsg:"note.missing"`
+}
+
+var messageTestChecks = map[string]Check{
+	"NotEmpty": DefaultChecks["NotEmpty"],
+	"Upper": func(v reflect.Value) bool {
+		return strings.ToUpper(v.String()) == v.String() && v.String() != ""
+	},
+}
+
+func TestMessagesFallBackToCheckNames(t *testing.T) {
+	err := CustomValidate(messageTestCustom{Note: "x"}, BuildTagCheckFinder(messageTestChecks))
+	require.IsType(t, ErrorChecksFailed{}, err)
+	assert.Equal(t, []string{"Code: Upper", "Code: must not be empty"}, err.(ErrorChecksFailed).UserMessages())
+}
+
+func TestWithMessages(t *testing.T) {
+	finder := BuildTagCheckFinder(messageTestChecks)
+	// keys in checkmsg tags are resolved for each validation, even though the tag is cached
+	for _, note := range []string{"add a note", "a note is required"} {
+		err := CustomValidate(messageTestCustom{}, finder, WithMessages(map[string]string{
+			"Upper":        "must be upper case",
+			"NotEmpty":     "must be filled in",
+			"note.missing": note,
+		}))
+		require.IsType(t, ErrorChecksFailed{}, err)
+		assert.Equal(t, []string{
+			"Code: must be upper case",
+			"Code: must be filled in",
+			"Note: " + note,
+		}, err.(ErrorChecksFailed).UserMessages())
+	}
+	err := CustomValidate(messageTestCustom{}, finder, WithMessages(map[string]string{"Upper": "{{.Bogus}}"}))
+	assert.IsType(t, ErrorIllegalCheck{}, err)
+}
+
+func TestInvalidMessageTag(t *testing.T) {
+	// bad templates are reported even if their field passes
+	err := Validate(struct {
+		Name string `checks:"NotEmpty" checkmsg:"{{.Name"`
+	}{Name: "x"})
+	assert.IsType(t, ErrorIllegalCheck{}, err)
+	err = Validate(struct {
+		Name string `checks:"NotEmpty" checkmsg:"{{.Bogus}}"`
+	}{})
+	assert.IsType(t, ErrorIllegalCheck{}, err)
+}
+
+func TestFormMessages(t *testing.T) {
+	var s messageTestSignup
+	errs, err := DecodeAndValidateForm(url.Values{"Username": {"bob"}, "Age": {"3"}, "Password": {"correct horse"}}, &s, WithMessages(messageTestMessages))
+	require.NoError(t, err)
+	assert.Equal(t, FormErrors{
+		"Name":  {"must not be empty", "must have a length of at least 2"},
+		"Email": {"we need an email address"},
+	}, errs)
+}
@@ -0,0 +1,350 @@
+package structcheck
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// configures a single call to Validate or CustomValidate
+type Option func(*options)
+
+type options struct {
+	groups          map[string]bool
+	mask            []pathPattern // nil if all fields are checked
+	presence        *Presence     // nil if Required and Forbidden only look at values
+	untyped         bool
+	root            []string // names of the checks run on the root value
+	parallelism     int
+	exclusive       map[reflect.Type]bool // types that may only be reached through one pointer
+	unexported      UnexportedPolicy
+	embeddedNames   bool
+	limits          Limits
+	sensitive       map[reflect.Type]bool // types whose values are redacted from failures
+	omitValues      bool
+	types           *TypeRegistry     // nil means DefaultTypeChecks
+	maskChecked     *sync.Map         // if set, remembers unknownMaskPaths by type (for options shared by a Batch)
+	formatter       ValueFormatter    // nil means DefaultFormatter
+	messages        map[string]string // consulted before DefaultMessages
+	interfaceChecks map[string]bool   // checks that call Interface() on the values they check
+	err             error             // the first invalid option encountered
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		groups: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Runs checks assigned to any of groups (e.g. `checks:"Nil@create,NotNil@update|sync"`) in addition to ungrouped checks. Without this option only ungrouped checks run.
+func WithGroups(groups ...string) Option {
+	return func(o *options) {
+		for _, group := range groups {
+			o.groups[group] = true
+		}
+	}
+}
+
+// Only runs checks on fields matching one of paths (dotted paths as used by CheckFieldsExist, with the wildcards accepted by BuildStringyCheckFinder) and on everything below them. The parents of included fields are traversed but not checked. CustomValidate returns ErrorUnknownFields if a path doesn't match any field of the validated type.
+func WithFieldMask(paths ...string) Option {
+	return func(o *options) {
+		patterns, err := parsePathPatterns(paths)
+		if err != nil {
+			o.setErr(err)
+			return
+		}
+		for _, pattern := range patterns {
+			o.mask = append(o.mask, pattern.subtree())
+		}
+	}
+}
+
+// Validates untyped data such as JSON decoded into interface{}. The root may be a map or slice instead of a struct, map entries with string keys are named like fields (e.g. Items[2].name instead of Items[2][name]) and interface values don't add a (Type) segment to field paths.
+func WithUntyped() Option {
+	return func(o *options) {
+		o.untyped = true
+	}
+}
+
+// Runs the named checks (or checks from DefaultParamChecks, e.g. "MaxLen(100)") on the root value. Useful for slice and map roots. Names are resolved against the check set of the CheckFinder at validation time; an unknown name is an ErrorIllegalCheck.
+func WithRootChecks(checkNames ...string) Option {
+	return func(o *options) {
+		o.root = append(o.root, checkNames...)
+	}
+}
+
+// Visits up to n nodes of the object graph concurrently. Results are identical to sequential validation. The CheckFinder and any custom checks must be safe for concurrent use.
+func WithParallelism(n int) Option {
+	return func(o *options) {
+		o.parallelism = n
+	}
+}
+
+// Fails the Exclusive check on any pointer to a value of one of the given types that was already reached through another field. Pointers that lead back to one of their own parents (cycles) don't count. Pointer types stand for the types they point to.
+func WithExclusiveOwnership(types ...reflect.Type) Option {
+	return func(o *options) {
+		if o.exclusive == nil {
+			o.exclusive = make(map[reflect.Type]bool)
+		}
+		for _, t := range types {
+			if t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			o.exclusive[t] = true
+		}
+	}
+}
+
+// Redacts the values of the given types, and of everything below them, from failures (see RedactedValue). Pointer types stand for the types they point to.
+func WithSensitiveTypes(types ...reflect.Type) Option {
+	return func(o *options) {
+		if o.sensitive == nil {
+			o.sensitive = make(map[reflect.Type]bool)
+		}
+		for _, t := range types {
+			o.sensitive[derefType(t)] = true
+		}
+	}
+}
+
+// Renders failing values with f instead of DefaultFormatter. Sensitive values are redacted before f sees them.
+func WithFormatter(f ValueFormatter) Option {
+	return func(o *options) {
+		o.formatter = f
+	}
+}
+
+// Adds message templates for failed checks (e.g. for the checks of a custom checkSet), keyed like DefaultMessages. They take precedence over DefaultMessages.
+func WithMessages(messages map[string]string) Option {
+	return func(o *options) {
+		if o.messages == nil {
+			o.messages = make(map[string]string)
+		}
+		for key, text := range messages {
+			o.messages[key] = text
+		}
+	}
+}
+
+// returns the message template with key from WithMessages or DefaultMessages
+func (o *options) message(key string) (string, bool) {
+	if o != nil {
+		if text, ok := o.messages[key]; ok {
+			return text, true
+		}
+	}
+	text, ok := DefaultMessages[key]
+	return text, ok
+}
+
+// Leaves Field.Value empty for all failures, so no values end up in errors.
+func WithoutValues() Option {
+	return func(o *options) {
+		o.omitValues = true
+	}
+}
+
+// how the traversal treats unexported struct fields
+type UnexportedPolicy int
+
+const (
+	// Unexported fields are traversed and their checks run, except for checks that need Interface() (which panics on values read from unexported fields), which are reported as skipped. Custom checks that need it must be declared with WithInterfaceChecks. This is the default.
+	UnexportedTraverse UnexportedPolicy = iota
+	// Unexported fields and everything below them are ignored
+	UnexportedSkip
+	// Unexported fields are traversed, but a checks tag on one is an ErrorIllegalCheck
+	UnexportedIllegal
+)
+
+// Sets how unexported struct fields are treated. Embedded structs aren't considered unexported because their exported fields are promoted.
+func WithUnexportedPolicy(p UnexportedPolicy) Option {
+	return func(o *options) {
+		o.unexported = p
+	}
+}
+
+func (o *options) unexportedPolicy() UnexportedPolicy {
+	if o == nil {
+		return UnexportedTraverse
+	}
+	return o.unexported
+}
+
+// Names the fields of embedded structs after the embedded type (e.g. Outer.Base.ID), instead of as fields of the struct embedding them (Outer.ID) like Go selectors and encoding/json do. Field masks and presence information follow the same naming.
+func WithEmbeddedTypeNames() Option {
+	return func(o *options) {
+		o.embeddedNames = true
+	}
+}
+
+// Declares custom checks that call Interface() on the values they check. Values read from unexported fields can't be converted to interface{}, so these checks are skipped on them and listed in ErrorChecksFailed.Skipped instead of panicking. The built-in checks don't need Interface().
+func WithInterfaceChecks(checkNames ...string) Option {
+	return func(o *options) {
+		if o.interfaceChecks == nil {
+			o.interfaceChecks = make(map[string]bool)
+		}
+		for _, name := range checkNames {
+			o.interfaceChecks[name] = true
+		}
+	}
+}
+
+// true if the check named name (which may have a ptr: or elem: prefix) was declared with WithInterfaceChecks
+func (o *options) needsInterface(name string) bool {
+	if o == nil {
+		return false
+	}
+	_, name = splitCheckLevel(name)
+	return o.interfaceChecks[name]
+}
+
+func (o *options) keepsEmbeddedNames() bool {
+	return o != nil && o.embeddedNames
+}
+
+// Bounds the work done validating untrusted input. Zero fields are unlimited.
+type Limits struct {
+	MaxDepth    int // the number of fields, elements and map entries between the root and the deepest value visited
+	MaxNodes    int // the number of values visited
+	MaxElements int // the number of elements or entries in any one slice, array or map that is traversed
+	MaxFailures int // the number of failing fields recorded
+}
+
+// Stops validation with an ErrorLimitExceeded when one of the limits is exceeded. Limits are hit at the same field whether or not WithParallelism is used.
+func WithLimits(l Limits) Option {
+	return func(o *options) {
+		o.limits = l
+	}
+}
+
+// returns an ErrorLimitExceeded if v is a container with too many elements to traverse
+func (o *options) checkElements(v metaValue) error {
+	if o.limits.MaxElements <= 0 || v.visited || v.noDescend {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if traversesElements(v.Type()) && v.Len() > o.limits.MaxElements {
+			return newErrorLimitExceeded("MaxElements", o.limits.MaxElements, v)
+		}
+	}
+	return nil
+}
+
+// Runs the type checks registered in r instead of the ones in DefaultTypeChecks.
+func WithTypeRegistry(r *TypeRegistry) Option {
+	return func(o *options) {
+		o.types = r
+	}
+}
+
+func (o *options) typeRegistry() *TypeRegistry {
+	if o == nil || o.types == nil {
+		return DefaultTypeChecks
+	}
+	return o.types
+}
+
+// Makes the Required and Forbidden checks look at whether fields were present in a decoded document (see DecodeJSON) instead of at their values
+func WithPresence(p *Presence) Option {
+	return func(o *options) {
+		o.presence = p
+	}
+}
+
+// checks whose outcome depends on field presence when presence information is available
+var presenceChecks = map[string]func(present bool) bool{
+	"Required":  func(present bool) bool { return present },
+	"Forbidden": func(present bool) bool { return !present },
+}
+
+// returns the presence-based replacement for the named check, or nil if it doesn't have one
+func (o *options) presenceCheck(checkName string, v metaValue) Check {
+	if o == nil || o.presence == nil {
+		return nil
+	}
+	check, ok := presenceChecks[checkName]
+	if !ok {
+		return nil
+	}
+	present := o.presence.Has(joinName(v.Name[1:]))
+	return func(reflect.Value) bool {
+		return check(present)
+	}
+}
+
+func (o *options) setErr(err error) {
+	if o.err == nil {
+		o.err = err
+	}
+}
+
+// reports whether v's checks run and whether its children are traversed under the field mask
+func (o *options) inMask(v metaValue) (check bool, descend bool) {
+	if o.mask == nil {
+		return true, true
+	}
+	for _, pattern := range o.mask {
+		states := pattern.run(v.Name[1:], v.Types[1:])
+		if states == nil {
+			continue
+		}
+		if states[len(pattern.segments)] {
+			return true, true
+		}
+		descend = true
+	}
+	return false, descend
+}
+
+// returns the mask paths that don't match any field reachable from t
+func (o *options) unknownMaskPaths(t reflect.Type) []string {
+	if o.maskChecked == nil {
+		return o.findUnknownMaskPaths(t)
+	}
+	if unknown, ok := o.maskChecked.Load(t); ok {
+		return unknown.([]string)
+	}
+	unknown := o.findUnknownMaskPaths(t)
+	o.maskChecked.Store(t, unknown)
+	return unknown
+}
+
+func (o *options) findUnknownMaskPaths(t reflect.Type) []string {
+	unknown := []string{}
+	for _, pattern := range o.mask {
+		if !pattern.matchesType(t, o.embeddedNames) {
+			unknown = append(unknown, pattern.text)
+		}
+	}
+	return unknown
+}
+
+// true if a check assigned to groups should run. Ungrouped checks always run.
+func (o *options) groupsActive(groups []string) bool {
+	if len(groups) == 0 {
+		return true
+	}
+	for _, group := range groups {
+		if o.groups[group] {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *options) activeGroups() []string {
+	if len(o.groups) == 0 {
+		return nil
+	}
+	groups := make([]string, 0, len(o.groups))
+	for group := range o.groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
@@ -5,6 +5,27 @@ Checks that constraints on structs are met. Constraints are read as a comma-deli
 
 See the Checks map for the full list of built-in checks.
 
+Checks may be assigned to one or more groups with an @ suffix, e.g. `checks:"Nil@create,NotNil@update|sync"`. Grouped checks only run when one of their groups is selected with the WithGroups option; ungrouped checks always run.
+
+On pointer fields, the checks in PointerChecks (NotNil, Nil, Nilable, Required and Forbidden) look at the pointer itself and all other checks look at the value it points to, so `checks:"NotNil,Positive"` on an *int requires a positive number. Checks on the pointed-to value are skipped when the pointer is nil. A ptr: prefix runs a check on the pointer and an elem: prefix runs it on the pointed-to value, failing if the pointer is nil. Failures of prefixed checks are reported with their prefix (e.g. elem:Positive):
+    Count   *int `checks:"Positive"`      // nil, or a positive number
+    Total   *int `checks:"elem:Positive"` // a positive number
+    Handles *[]int `checks:"ptr:NotNil,elem:NotNil"`
+
+A few reserved entries in the checks tag control the traversal itself, whichever CheckFinder is used: "-" ignores a field entirely, "nodescend" checks a field but nothing below it, "notagsbelow" ignores the checks tags of everything below a field (e.g. `checks:"NotNil,nodescend"` on a *sync.Mutex), and "sensitive" redacts the values of a field and everything below it from failures.
+
+Unexported fields are traversed and their checks run by default, but checks that need Interface() (which isn't allowed on values read from unexported fields) are skipped on them and listed in ErrorChecksFailed.Skipped. The built-in checks don't need Interface(); custom checks that do must be declared with WithInterfaceChecks, since panics from checks aren't recovered. WithUnexportedPolicy can skip unexported fields entirely (UnexportedSkip) or reject checks tags on them (UnexportedIllegal).
+
+Fields of embedded structs (including embedded pointers) are named as fields of the struct embedding them, like Go selectors and encoding/json do: the ID field of an embedded Base in Outer is Outer.ID, unless Outer has an ID field of its own, in which case it stays Outer.Base.ID. Fields aren't promoted through embedded interfaces. WithEmbeddedTypeNames keeps embedded types in all paths.
+
+The elements of slices, arrays and maps are traversed like fields, so the checks of structs inside them run too. Elements are named by index or key (e.g. Order.Lines[0].SKU) and map entries are visited in key order. Byte slices and arrays are checked as single values. Earlier versions only checked the containers themselves; tag a container `checks:"nodescend"` to keep that behavior for it.
+
+ErrorChecksFailed.Messages holds a user-facing message for each failed check, rendered from the templates in DefaultMessages. WithMessages adds or replaces templates, e.g. for the checks of a custom checkSet. A checkmsg tag overrides them, e.g. `checkmsg:"NotEmpty=is required;MinLen=needs {{.Param}} characters"`.
+
+Failures carry the failing values in Field.Value, which ends up in error messages. Values tagged sensitive, of types passed to WithSensitiveTypes, or containing either are replaced with RedactedValue; types implementing Redactor render themselves. WithoutValues leaves all values out. Other values are rendered by DefaultFormatter, which truncates long values and summarizes large containers; WithFormatter replaces it.
+
+When validating untrusted input, WithLimits bounds the traversal's depth, the number of values visited, the size of traversed containers and the number of failures recorded. Exceeding a limit stops validation with an ErrorLimitExceeded naming the limit and the field where it was hit.
+
 Example:
     package main
 
@@ -0,0 +1,449 @@
+package structcheck
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// joins field path segments, attaching index segments (e.g. [3]) without a dot
+func joinName(segments []string) string {
+	buf := new(strings.Builder)
+	for i, seg := range segments {
+		if i != 0 && !strings.HasPrefix(seg, "[") {
+			buf.WriteString(".")
+		}
+		buf.WriteString(seg)
+	}
+	return buf.String()
+}
+
+// splits a dotted path into segments. Dots inside brackets and parentheses don't split, and [...] always starts a new segment.
+func splitPath(path string) ([]string, error) {
+	segments := []string{}
+	buf := new(strings.Builder)
+	flush := func() {
+		if buf.Len() != 0 {
+			segments = append(segments, buf.String())
+			buf.Reset()
+		}
+	}
+	depth := 0
+	for _, r := range path {
+		switch {
+		case r == '[' && depth == 0:
+			flush()
+			buf.WriteRune(r)
+			depth++
+		case r == ']' && depth == 1 && strings.HasPrefix(buf.String(), "["):
+			buf.WriteRune(r)
+			depth--
+			flush()
+		case r == '(' || r == '[':
+			buf.WriteRune(r)
+			depth++
+		case r == ')' || r == ']':
+			buf.WriteRune(r)
+			depth--
+		case r == '.' && depth == 0:
+			if buf.Len() == 0 && (len(segments) == 0 || !strings.HasPrefix(segments[len(segments)-1], "[")) {
+				return nil, fmt.Errorf("Empty segment in path: %v", path)
+			}
+			flush()
+		default:
+			buf.WriteRune(r)
+		}
+		if depth < 0 {
+			return nil, fmt.Errorf("Unbalanced brackets in path: %v", path)
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("Unbalanced brackets in path: %v", path)
+	}
+	flush()
+	return segments, nil
+}
+
+type segmentKind int
+
+// ordered from least to most specific
+const (
+	segmentAnyDepth segmentKind = iota // **
+	segmentAny                         // * or [*]
+	segmentType                        // (TypeName)
+	segmentLiteral                     // Name or [3]
+)
+
+type patternSegment struct {
+	kind  segmentKind
+	text  string
+	index bool // true if the segment matches element indices or map keys
+}
+
+func isIndexSegment(seg string) bool {
+	return strings.HasPrefix(seg, "[")
+}
+
+// true if the path segment seg (naming a node of type t) matches this pattern segment
+func (p patternSegment) matches(seg string, t reflect.Type) bool {
+	switch p.kind {
+	case segmentAnyDepth:
+		return true
+	case segmentAny:
+		return p.index == isIndexSegment(seg)
+	case segmentType:
+		if seg == p.text {
+			return true
+		}
+		if t == nil || isIndexSegment(seg) {
+			return false
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		name := p.text[1 : len(p.text)-1]
+		return t.Name() == name || t.String() == name
+	default:
+		return seg == p.text
+	}
+}
+
+// A glob-style field path pattern. Segments may be literal field names or indices, * (any single field), [*] (any element index or map key), ** (any number of segments) or (TypeName) (any single field whose value has the named type).
+type pathPattern struct {
+	text     string
+	segments []patternSegment
+}
+
+func parsePathPattern(text string) (pathPattern, error) {
+	split, err := splitPath(text)
+	if err != nil {
+		return pathPattern{}, err
+	}
+	segments := make([]patternSegment, len(split))
+	for i, seg := range split {
+		switch {
+		case seg == "**":
+			segments[i] = patternSegment{kind: segmentAnyDepth, text: seg}
+		case seg == "*":
+			segments[i] = patternSegment{kind: segmentAny, text: seg}
+		case seg == "[*]":
+			segments[i] = patternSegment{kind: segmentAny, text: seg, index: true}
+		case strings.HasPrefix(seg, "(") && strings.HasSuffix(seg, ")") && len(seg) > 2:
+			segments[i] = patternSegment{kind: segmentType, text: seg}
+		case strings.Contains(seg, "*"):
+			return pathPattern{}, fmt.Errorf("Wildcards must make up a whole segment in path pattern: %v", text)
+		default:
+			segments[i] = patternSegment{kind: segmentLiteral, text: seg, index: isIndexSegment(seg)}
+		}
+	}
+	return pathPattern{text: text, segments: segments}, nil
+}
+
+// adds the positions reachable by letting ** match zero segments
+func (p pathPattern) closure(states []bool) []bool {
+	for pos := 0; pos < len(p.segments); pos++ {
+		if states[pos] && p.segments[pos].kind == segmentAnyDepth {
+			states[pos+1] = true
+		}
+	}
+	return states
+}
+
+// runs the pattern over a path and returns the set of pattern positions that are still live
+func (p pathPattern) run(names []string, types []reflect.Type) []bool {
+	states := make([]bool, len(p.segments)+1)
+	states[0] = true
+	states = p.closure(states)
+	for i, name := range names {
+		var t reflect.Type
+		if i < len(types) {
+			t = types[i]
+		}
+		next := make([]bool, len(p.segments)+1)
+		alive := false
+		for pos, seg := range p.segments {
+			if !states[pos] || !seg.matches(name, t) {
+				continue
+			}
+			alive = true
+			if seg.kind == segmentAnyDepth {
+				next[pos] = true
+			} else {
+				next[pos+1] = true
+			}
+		}
+		if !alive {
+			return nil
+		}
+		states = p.closure(next)
+	}
+	return states
+}
+
+// true if the pattern matches the whole path
+func (p pathPattern) Match(names []string, types []reflect.Type) bool {
+	states := p.run(names, types)
+	return states != nil && states[len(p.segments)]
+}
+
+// returns a pattern matching everything p matches and everything below it
+func (p pathPattern) subtree() pathPattern {
+	segments := make([]patternSegment, len(p.segments), len(p.segments)+1)
+	copy(segments, p.segments)
+	p.segments = append(segments, patternSegment{kind: segmentAnyDepth, text: "**"})
+	return p
+}
+
+// true if p is more specific than q. The leftmost segment that differs in specificity decides; longer patterns beat their prefixes, and ties fall back to lexical order.
+func (p pathPattern) moreSpecific(q pathPattern) bool {
+	for i := 0; i < len(p.segments) && i < len(q.segments); i++ {
+		if p.segments[i].kind != q.segments[i].kind {
+			return p.segments[i].kind > q.segments[i].kind
+		}
+	}
+	if len(p.segments) != len(q.segments) {
+		return len(p.segments) > len(q.segments)
+	}
+	return p.text < q.text
+}
+
+// sorts patterns from most to least specific
+type byPatternPrecedence []pathPattern
+
+func (a byPatternPrecedence) Len() int {
+	return len(a)
+}
+
+func (a byPatternPrecedence) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+func (a byPatternPrecedence) Less(i, j int) bool {
+	return a[i].moreSpecific(a[j])
+}
+
+func sortPatterns(patterns []pathPattern) {
+	sort.Sort(byPatternPrecedence(patterns))
+}
+
+// a node in a type graph walk
+type typeChild struct {
+	name  string
+	index bool
+	reflect.Type
+}
+
+// lists the children the traversal can reach from a value of type t. Unless embeddedNames is set, fields promoted from embedded structs are children of t.
+func typeChildren(t reflect.Type, embeddedNames bool) []typeChild {
+	switch t.Kind() {
+	case reflect.Struct:
+		children := make([]typeChild, t.NumField())
+		for i := range children {
+			f := t.Field(i)
+			children[i] = typeChild{name: f.Name, Type: f.Type}
+		}
+		if !embeddedNames {
+			for _, f := range promotedFields(t) {
+				children = append(children, typeChild{name: f.Name, Type: f.Type})
+			}
+		}
+		return children
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if !traversesElements(t) {
+			return nil
+		}
+		return []typeChild{{name: "[*]", index: true, Type: t.Elem()}}
+	}
+	return nil
+}
+
+// true if the type-level segment c could be named by the pattern segment p
+func (p patternSegment) matchesChild(c typeChild) bool {
+	if p.kind == segmentLiteral && p.index {
+		return c.index
+	}
+	if c.index {
+		return p.kind == segmentAnyDepth || (p.kind == segmentAny && p.index)
+	}
+	return p.matches(c.name, c.Type)
+}
+
+// true if some field reachable from type t could match the pattern. Patterns that pass through interface values can't be ruled out and are assumed to match.
+func (p pathPattern) MatchesType(t reflect.Type) bool {
+	return p.matchesType(t, false)
+}
+
+// MatchesType, with fields of embedded structs named as in WithEmbeddedTypeNames if embeddedNames is set
+func (p pathPattern) matchesType(t reflect.Type, embeddedNames bool) bool {
+	type state struct {
+		reflect.Type
+		pos int
+	}
+	visited := make(map[state]bool)
+	var visit func(t reflect.Type, pos int) bool
+	visit = func(t reflect.Type, pos int) bool {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if pos == len(p.segments) || t.Kind() == reflect.Interface {
+			return true
+		}
+		if visited[state{t, pos}] {
+			return false
+		}
+		visited[state{t, pos}] = true
+		seg := p.segments[pos]
+		if seg.kind == segmentAnyDepth && visit(t, pos+1) {
+			return true
+		}
+		for _, c := range typeChildren(t, embeddedNames) {
+			if !seg.matchesChild(c) {
+				continue
+			}
+			next := pos + 1
+			if seg.kind == segmentAnyDepth {
+				next = pos
+			}
+			if visit(c.Type, next) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(t, 0)
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// true if f is an embedded struct (or pointer to one), whose fields are promoted to the struct containing it. Fields aren't promoted through embedded interfaces.
+func isEmbeddedStruct(f reflect.StructField) bool {
+	return f.Anonymous && derefType(f.Type).Kind() == reflect.Struct
+}
+
+// true if the field named name at index within struct type t can be referred to by its name alone (i.e. it isn't hidden by a shallower field or ambiguous)
+func isPromoted(t reflect.Type, name string, index []int) bool {
+	if len(index) == 1 {
+		return true
+	}
+	f, ok := derefType(t).FieldByName(name)
+	if !ok || len(f.Index) != len(index) {
+		return false
+	}
+	for i := range index {
+		if f.Index[i] != index[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// lists the fields of embedded structs that are promoted to struct type t, with their indices relative to t
+func promotedFields(t reflect.Type) []reflect.StructField {
+	fields := []reflect.StructField{}
+	visited := map[reflect.Type]bool{t: true}
+	var walk func(embedded reflect.Type, index []int)
+	walk = func(embedded reflect.Type, index []int) {
+		embedded = derefType(embedded)
+		if visited[embedded] {
+			return
+		}
+		visited[embedded] = true
+		for i := 0; i < embedded.NumField(); i++ {
+			f := embedded.Field(i)
+			f.Index = appendIndex(index, i)
+			if isPromoted(t, f.Name, f.Index) {
+				fields = append(fields, f)
+			}
+			if isEmbeddedStruct(f) {
+				walk(f.Type, f.Index)
+			}
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); isEmbeddedStruct(f) {
+			walk(f.Type, []int{i})
+		}
+	}
+	return fields
+}
+
+func appendIndex(index []int, i int) []int {
+	extended := make([]int, len(index), len(index)+1)
+	copy(extended, index)
+	return append(extended, i)
+}
+
+// the traversal path segments naming the field at index, starting from struct type t. Unless embeddedNames is set, promoted fields of embedded structs are named as if they were fields of the struct embedding them.
+func goFieldPath(t reflect.Type, index []int, embeddedNames bool) []string {
+	segments := make([]string, 0, len(index))
+	// fields are promoted to scope, which starts at index[start] and segments[scopeLen]
+	scope, start, scopeLen := t, 0, 0
+	for i, n := range index {
+		t = derefType(t)
+		f := t.Field(n)
+		if !embeddedNames && isPromoted(scope, f.Name, index[start:i+1]) {
+			segments = append(segments[:scopeLen], f.Name)
+		} else {
+			segments = append(segments, f.Name)
+		}
+		if embeddedNames || !isEmbeddedStruct(f) {
+			scope, start, scopeLen = f.Type, i+1, len(segments)
+		}
+		t = f.Type
+	}
+	return segments
+}
+
+// Resolves a dotted field name the way Go selectors do (promoted fields may omit the embedded struct) to the paths the traversal uses for the field and every field above it, including embedded structs. Returns false if t has no such field.
+func resolveFieldPaths(t reflect.Type, name string, embeddedNames bool) ([][]string, bool) {
+	paths := [][]string{}
+	path := []string{}
+	scope, index, scopeLen := t, []int{}, 0
+	current := t
+	for _, seg := range strings.Split(name, ".") {
+		current = derefType(current)
+		if current.Kind() != reflect.Struct {
+			return nil, false
+		}
+		f, ok := current.FieldByName(seg)
+		if !ok {
+			return nil, false
+		}
+		for _, i := range f.Index {
+			index = append(index, i)
+			path = appendPath(path[:scopeLen], goFieldPath(scope, index, embeddedNames)...)
+			paths = append(paths, path)
+		}
+		if embeddedNames || !isEmbeddedStruct(f) {
+			scope, index, scopeLen = f.Type, []int{}, len(path)
+		}
+		current = f.Type
+	}
+	return paths, true
+}
+
+// Names the fields of a struct type while walking it field by field, the way the traversal does
+type structScope struct {
+	t     reflect.Type // the struct that fields are promoted to
+	path  []string     // the path of that struct
+	qpath []string     // the path of that struct under WithEmbeddedTypeNames
+	index []int        // the index of the embedded struct being walked within t, if any
+}
+
+// returns the paths of field i of the struct being walked (by default and under WithEmbeddedTypeNames), and the scope for walking the field's own fields
+func (s structScope) field(i int) ([]string, []string, structScope) {
+	index := appendIndex(s.index, i)
+	path := appendPath(s.path, goFieldPath(s.t, index, false)...)
+	qpath := appendPath(s.qpath, goFieldPath(s.t, index, true)...)
+	f := derefType(s.t).FieldByIndex(index)
+	if isEmbeddedStruct(f) {
+		return path, qpath, structScope{t: s.t, path: s.path, qpath: s.qpath, index: index}
+	}
+	return path, qpath, structScope{t: f.Type, path: path, qpath: qpath}
+}
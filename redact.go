@@ -0,0 +1,212 @@
+package structcheck
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// the Field.Value of failures whose values are sensitive
+const RedactedValue = "[REDACTED]"
+
+// Implemented by types that render their own values in failures, e.g. a card number that only shows its last digits.
+type Redactor interface {
+	Redacted() string
+}
+
+var redactorType = reflect.TypeOf((*Redactor)(nil)).Elem()
+
+// Renders v for Field.Value. Values are redacted if they're tagged sensitive, are of or below a sensitive type, or contain a sensitive field or Redactor that %#v would print (see holdsSensitive). Nil values aren't redacted since they hold nothing to hide.
+func (o *options) formatValue(v metaValue) string {
+	if o != nil && o.omitValues {
+		return ""
+	}
+	if !isNilValue(v.Value) {
+		if v.sensitive || o.belowSensitiveType(v.Types) {
+			return RedactedValue
+		}
+		if r, ok := asRedactor(v.Value); ok {
+			return r.Redacted()
+		}
+		if o.holdsSensitive(v.Value) {
+			return RedactedValue
+		}
+	}
+	format := DefaultFormatter.Format
+	if o != nil && o.formatter != nil {
+		format = o.formatter
+	}
+	if !v.CanInterface() {
+		return fmt.Sprintf("unexported field (%v)", format(v.Value))
+	}
+	return format(v.Value)
+}
+
+// returns v as a Redactor, using its address if Redacted has a pointer receiver
+func asRedactor(v reflect.Value) (Redactor, bool) {
+	if i, ok := asImplementer(v, redactorType); ok {
+		return i.(Redactor), true
+	}
+	return nil, false
+}
+
+// returns v (or its address, if the methods of interface type t have pointer receivers) as an interface{} implementing t
+func asImplementer(v reflect.Value, t reflect.Type) (interface{}, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	if v.Type().Implements(t) {
+		return v.Interface(), true
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(t) {
+		return v.Addr().Interface(), true
+	}
+	return nil, false
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+		return v.IsNil()
+	}
+	return false
+}
+
+// true if any of types (the types of a node and its parents) was registered with WithSensitiveTypes
+func (o *options) belowSensitiveType(types []reflect.Type) bool {
+	if o == nil || len(o.sensitive) == 0 {
+		return false
+	}
+	for _, t := range types {
+		if o.sensitive[derefType(t)] {
+			return true
+		}
+	}
+	return false
+}
+
+// the number of values holdsSensitive looks at before giving up and treating a value as sensitive
+const maxSensitiveScan = 1000
+
+// a search of a value for sensitive fields and Redactors
+type sensitiveScan struct {
+	o          *options
+	budget     int // the number of values left to look at
+	seen       map[visitKey]bool
+	sensitive  map[reflect.Type]bool // memoized containsSensitive
+	interfaces map[reflect.Type]bool // memoized holdsInterfaces
+}
+
+// true if v holds a sensitive field or Redactor. The static types of values are enough unless they can hold interfaces, in which case the dynamic values are looked into as well. The search is bounded (by maxSensitiveScan and MaxNodes), and values too large to search are treated as sensitive.
+func (o *options) holdsSensitive(v reflect.Value) bool {
+	s := &sensitiveScan{
+		o:          o,
+		budget:     maxSensitiveScan,
+		seen:       make(map[visitKey]bool),
+		sensitive:  make(map[reflect.Type]bool),
+		interfaces: make(map[reflect.Type]bool),
+	}
+	if o != nil && o.limits.MaxNodes > 0 && o.limits.MaxNodes < s.budget {
+		s.budget = o.limits.MaxNodes
+	}
+	return s.holds(v)
+}
+
+func (s *sensitiveScan) holds(v reflect.Value) bool {
+	t := v.Type()
+	sensitive, ok := s.sensitive[t]
+	if !ok {
+		sensitive = s.o.containsSensitive(t, map[reflect.Type]bool{})
+		s.sensitive[t] = sensitive
+	}
+	if sensitive {
+		return true
+	}
+	interfaces, ok := s.interfaces[t]
+	if !ok {
+		interfaces = holdsInterfaces(t, map[reflect.Type]bool{})
+		s.interfaces[t] = interfaces
+	}
+	if isNilValue(v) || !interfaces {
+		return false
+	}
+	if s.budget--; s.budget < 0 {
+		return true
+	}
+	if key, ok := newVisitKey(v); ok {
+		if s.seen[key] {
+			return false
+		}
+		s.seen[key] = true
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		return s.holds(v.Elem())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if s.holds(v.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			if s.holds(iter.Key()) || s.holds(iter.Value()) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if s.holds(v.Field(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// true if values of type t may hold interfaces
+func holdsInterfaces(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return holdsInterfaces(t.Elem(), seen)
+	case reflect.Map:
+		return holdsInterfaces(t.Key(), seen) || holdsInterfaces(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if holdsInterfaces(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// true if values of type t may hold a sensitive field or Redactor. The dynamic values of interfaces can't be known from t and aren't counted.
+func (o *options) containsSensitive(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	if t.Implements(redactorType) || reflect.PtrTo(t).Implements(redactorType) || (o != nil && o.sensitive[t]) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return o.containsSensitive(t.Elem(), seen)
+	case reflect.Map:
+		return o.containsSensitive(t.Key(), seen) || o.containsSensitive(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if hasDirective(f.Tag, DirectiveSensitive) || o.containsSensitive(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package structcheck
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type redactCard string
+
+func (c redactCard) Redacted() string {
+	return "card ending " + string(c[len(c)-4:])
+}
+
+type redactToken struct {
+	Value string `checks:"MinLen(8)"`
+}
+
+type redactCredentials struct {
+	User     string `checks:"MinLen(3)"`
+	Password string `checks:"sensitive,MinLen(8)"`
+}
+
+type redactAccount struct {
+	Login    redactCredentials `checks:"Zero"`
+	Secrets  *redactCredentials
+	Token    redactToken
+	Card     redactCard `checks:"MaxLen(4)"`
+	Nickname string     `checks:"MinLen(3)"`
+	Hidden   struct {
+		Key string `checks:"MinLen(8)"`
+	} `checks:"sensitive"`
+}
+
+func valuesByPath(t *testing.T, err error) map[string]string {
+	require.IsType(t, ErrorChecksFailed{}, err)
+	values := map[string]string{}
+	for field := range err.(ErrorChecksFailed).Field2Checks {
+		values[field.Path] = field.Value
+	}
+	return values
+}
+
+func newRedactAccount() redactAccount {
+	a := redactAccount{
+		Login:    redactCredentials{User: "al", Password: "hunter2"},
+		Token:    redactToken{Value: "abc123"},
+		Card:     "4111111111111111",
+		Nickname: "x",
+	}
+	a.Hidden.Key = "k3y"
+	return a
+}
+
+func TestRedaction(t *testing.T) {
+	err := Validate(newRedactAccount(), WithSensitiveTypes(reflect.TypeOf(&redactToken{})))
+	assert.Equal(t, map[string]string{
+		"Login":          RedactedValue, // %#v would print Password
+		"Login.User":     `"al"`,
+		"Login.Password": RedactedValue,
+		"Token.Value":    RedactedValue,
+		"Card":           "card ending 1111",
+		"Nickname":       `"x"`,
+		"Hidden.Key":     RedactedValue,
+	}, valuesByPath(t, err))
+	assert.NotContains(t, err.Error(), "hunter2")
+	assert.NotContains(t, err.Error(), "k3y")
+	assert.NotContains(t, err.Error(), "4111111111111111")
+}
+
+func TestRedactionKeepsNils(t *testing.T) {
+	err := Validate(struct {
+		Secrets *redactCredentials `checks:"NotNil,sensitive"`
+	}{})
+	assert.Equal(t, map[string]string{"Secrets": "(*structcheck.redactCredentials)(nil)"}, valuesByPath(t, err))
+}
+
+func TestWithoutValues(t *testing.T) {
+	err := Validate(newRedactAccount(), WithoutValues())
+	for path, value := range valuesByPath(t, err) {
+		assert.Empty(t, value, path)
+	}
+	assert.NotContains(t, err.Error(), `"x"`)
+	assert.True(t, strings.HasSuffix(err.Error(), "redactAccount.Hidden.Key:     MinLen(8)"), err.Error())
+}
+
+func TestRedactedPointerLevels(t *testing.T) {
+	p, s := -1, -1
+	type levels struct {
+		P *int `checks:"ptr:Nil,elem:Positive"`
+		S *int `checks:"sensitive,ptr:Nil,elem:Positive"`
+	}
+	for _, opts := range [][]Option{nil, {WithoutValues()}} {
+		err := Validate(levels{P: &p, S: &s}, opts...)
+		require.IsType(t, ErrorChecksFailed{}, err)
+		checks := map[string][]string{}
+		for field, failed := range err.(ErrorChecksFailed).Field2Checks {
+			checks[field.Path] = append(checks[field.Path], failed...)
+		}
+		for _, list := range checks {
+			sort.Strings(list)
+		}
+		assert.Equal(t, map[string][]string{
+			"P": {"elem:Positive", "ptr:Nil"},
+			"S": {"elem:Positive", "ptr:Nil"},
+		}, checks)
+	}
+}
+
+type redactEnvelope struct {
+	Payload interface{}
+}
+
+func TestRedactionOfInterfaces(t *testing.T) {
+	err := Validate(struct {
+		Login redactEnvelope `checks:"Zero"`
+		Card  redactEnvelope `checks:"Zero"`
+		List  []interface{}  `checks:"Empty"`
+		Plain redactEnvelope `checks:"Zero"`
+	}{
+		Login: redactEnvelope{redactCredentials{User: "al", Password: "hunter2"}},
+		Card:  redactEnvelope{redactCard("4111111111111111")},
+		List:  []interface{}{1, &redactCredentials{Password: "hunter2"}},
+		Plain: redactEnvelope{redactToken{Value: "abc"}},
+	})
+	// %#v would print the dynamic values, so they're looked into
+	values := valuesByPath(t, err)
+	assert.Equal(t, RedactedValue, values["Login"])
+	assert.Equal(t, RedactedValue, values["Card"])
+	assert.Equal(t, RedactedValue, values["List"])
+	assert.Contains(t, values["Plain"], "abc")
+	assert.NotContains(t, err.Error(), "hunter2")
+	assert.NotContains(t, err.Error(), "4111111111111111")
+}
+
+func TestRedactionScanIsBounded(t *testing.T) {
+	doc := make([]interface{}, 1000000)
+	for i := range doc {
+		doc[i] = i
+	}
+	start := time.Now()
+	err := Validate(doc, WithUntyped(), WithRootChecks("Empty"), WithLimits(Limits{MaxElements: 10}))
+	require.IsType(t, ErrorLimitExceeded{}, err)
+	// values too large to search for sensitive data are redacted
+	for field := range err.(ErrorLimitExceeded).Field2Checks {
+		assert.Equal(t, RedactedValue, field.Value)
+	}
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestRedactionInJSON(t *testing.T) {
+	var a redactAccount
+	err := DecodeAndValidateJSON("body.json", strings.NewReader(`{"Nickname":"bob","Login":{"User":"alice","Password":"hunter2"}}`), &a)
+	require.IsType(t, ErrorJSONInvalid{}, err)
+	for _, f := range err.(ErrorJSONInvalid).Failures {
+		assert.NotContains(t, f.Value, "hunter2", f.Path)
+	}
+}
@@ -1,7 +1,12 @@
 package structcheck
 
 import (
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 func drillDown(v reflect.Value) (reflect.Value, error) {
@@ -17,27 +22,164 @@ func drillDown(v reflect.Value) (reflect.Value, error) {
 	return v, nil
 }
 
-func runChecks(v metaValue) ([]string, error) {
+// Runs v's checks and returns the names of the ones that failed and the ones that were skipped (see runCheck), along with the checks that have to wait for the value v points to. The CheckFinder only runs at the top of a chain of pointers, so each check runs once.
+func runChecks(v metaValue) ([]string, []string, []elemCheck, error) {
 	failedChecks := []string{}
-	checks, checkNames, err := v.getChecks()
-	if err != nil {
-		return nil, err
+	skippedChecks := []string{}
+	run := func(name string, check Check, needsInterface bool) {
+		switch runCheck(check, v.Value, needsInterface || v.opts.needsInterface(name)) {
+		case checkFailed:
+			failedChecks = append(failedChecks, name)
+		case checkSkipped:
+			skippedChecks = append(skippedChecks, name)
+		}
+	}
+	checkNames := []string{}
+	pending := v.elemChecks
+	if !v.pointee {
+		if v.unexported && v.opts.unexportedPolicy() == UnexportedIllegal && v.tag != nil && v.tag.Get("checks") != "" {
+			return nil, nil, nil, ErrorIllegalCheck{
+				value:  v,
+				Reason: "checks can't be applied to unexported fields",
+			}
+		}
+		checks, names, err := v.getChecks()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		// checkmsg tags are verified even if their field passes
+		if v.tag != nil {
+			if _, err := parseMessageTag(v.tag.Get(MessageTag)); err != nil {
+				return nil, nil, nil, ErrorIllegalCheck{value: v, Reason: fmt.Sprintf("invalid %v tag: %v", MessageTag, err)}
+			}
+		}
+		for i, check := range checks {
+			level, name := splitCheckLevel(names[i])
+			if presenceCheck := v.opts.presenceCheck(name, v); presenceCheck != nil {
+				check = presenceCheck
+			}
+			// failures are reported with their ptr: or elem: prefix, if the check had one
+			if level == levelPointer || v.Kind() != reflect.Ptr {
+				checkNames = append(checkNames, name)
+				run(names[i], check, false)
+				continue
+			}
+			pending = append(pending[:len(pending):len(pending)], elemCheck{
+				namedCheck: namedCheck{name: names[i], check: check},
+				strict:     level == levelElem,
+			})
+		}
+	}
+	var deferred []elemCheck
+	for _, c := range pending {
+		switch {
+		case v.Kind() != reflect.Ptr:
+			_, name := splitCheckLevel(c.name)
+			checkNames = append(checkNames, name)
+			run(c.name, c.check, false)
+		case !v.IsNil():
+			deferred = append(deferred, c)
+		case c.strict:
+			failedChecks = append(failedChecks, c.name)
+		}
+	}
+	// the root value also gets the checks from WithRootChecks
+	if len(v.Name) == 1 && v.opts != nil && len(v.opts.root) != 0 {
+		rootChecks, err := v.rootChecks()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, rootCheck := range rootChecks {
+			checkNames = append(checkNames[:len(checkNames):len(checkNames)], rootCheck.name)
+			run(rootCheck.name, rootCheck.check, false)
+		}
+	}
+	if v.unexpectedAlias {
+		failedChecks = append(failedChecks, "Exclusive")
+	}
+	// values reached again already had their type checks run
+	if v.visited && v.pointee {
+		return failedChecks, skippedChecks, deferred, nil
+	}
+	// type checks don't repeat checks the finder already ran
+	for _, typeCheck := range v.opts.typeRegistry().checksFor(v.Value) {
+		if containsString(checkNames, typeCheck.name) {
+			continue
+		}
+		checkNames = append(checkNames[:len(checkNames):len(checkNames)], typeCheck.name)
+		run(typeCheck.name, typeCheck.check, typeCheck.needsInterface)
+	}
+	return failedChecks, skippedChecks, deferred, nil
+}
+
+// the outcome of running a check
+type checkResult int
+
+const (
+	checkPassed checkResult = iota
+	checkFailed
+	checkSkipped
+)
+
+// Runs check on v. Values read from unexported fields can't be converted back to interface{}, so a check that needs Interface() is skipped on them.
+func runCheck(check Check, v reflect.Value, needsInterface bool) checkResult {
+	if needsInterface && !v.CanInterface() {
+		return checkSkipped
 	}
-	for i, check := range checks {
-		if !check(v.Value) {
-			failedChecks = append(failedChecks, checkNames[i])
+	if check(v) {
+		return checkPassed
+	}
+	return checkFailed
+}
+
+// true if values of type t are structs, or slices, arrays or maps of (pointers to) structs
+func drillsToStruct(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		t = t.Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
 		}
 	}
-	return failedChecks, nil
+	return t.Kind() == reflect.Struct
+}
+
+// names a root type the way Go code would, without package qualifiers (e.g. []Order or map[string]*Order)
+func typeName(t reflect.Type) string {
+	if t.Name() != "" {
+		return t.Name()
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + typeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + typeName(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%v", t.Len(), typeName(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map[%v]%v", typeName(t.Key()), typeName(t.Elem()))
+	case reflect.Struct:
+		return "(anonymous struct)"
+	}
+	return t.String()
 }
 
-// drills down (follows pointer and interface indirection) to a struct and recursively runs checks on all fields.
-func Validate(i interface{}) error {
-	return CustomValidate(i, BuildTagCheckFinder(DefaultChecks))
+// drills down (follows pointer and interface indirection) to a struct, or a slice, array or map of structs, and recursively runs checks on all fields.
+func Validate(i interface{}, opts ...Option) error {
+	return CustomValidate(i, BuildTagCheckFinder(DefaultChecks), opts...)
 }
 
 // runs Validate with a custom set of checks
-func CustomValidate(i interface{}, checkFinder CheckFinder) error {
+func CustomValidate(i interface{}, checkFinder CheckFinder, opts ...Option) error {
+	o := newOptions(opts)
+	if o.err != nil {
+		return o.err
+	}
+	return validate(i, checkFinder, o)
+}
+
+// runs CustomValidate with options that were already built
+func validate(i interface{}, checkFinder CheckFinder, o *options) error {
 	// find root node
 	if i == nil {
 		return ErrorNilValue{}
@@ -48,52 +190,299 @@ func CustomValidate(i interface{}, checkFinder CheckFinder) error {
 		return err
 	}
 
-	if top.Kind() != reflect.Struct {
-		return ErrorInvalidKind{Type: top.Type()}
+	name := typeName(top.Type())
+	if o.untyped {
+		// field masks can't be verified against untyped data
+		switch top.Kind() {
+		case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		default:
+			return ErrorInvalidKind{Type: top.Type()}
+		}
+		if top.Type().Name() == "" && top.Kind() != reflect.Struct {
+			name = "(document)"
+		}
+	} else {
+		if !drillsToStruct(top.Type()) {
+			return ErrorInvalidKind{Type: top.Type()}
+		}
+		if unknown := o.unknownMaskPaths(top.Type()); len(unknown) != 0 {
+			return ErrorUnknownFields{Type: top.Type(), Fields: unknown}
+		}
 	}
 
 	// Breadth first search
-	name := top.Type().Name()
-	if name == "" {
-		name = "(anonymous struct)"
-	}
 	namedTop := metaValue{
 		Value:       top,
 		Name:        []string{name},
+		Types:       []reflect.Type{top.Type()},
 		CheckFinder: checkFinder,
+		opts:        o,
+	}
+	// pointers back to the root are cycles
+	if top.CanAddr() {
+		namedTop.ancestors = &pointerChain{key: visitKey{Type: reflect.PtrTo(top.Type()), ptr: top.Addr().Pointer()}}
+	}
+	var failures *failureLog
+	if o.parallelism > 1 {
+		failures, err = traverseParallel(namedTop, o.parallelism)
+	} else {
+		failures, err = traverse(namedTop)
+	}
+	if limitErr, ok := err.(ErrorLimitExceeded); ok {
+		limitErr.Field2Checks = failures.checks
+		return limitErr
+	} else if err != nil {
+		return err
+	}
+
+	if len(failures.checks) != 0 {
+		return ErrorChecksFailed{
+			Field2Checks: failures.checks,
+			Groups:       o.activeGroups(),
+			Aliases:      failures.fieldAliases(),
+			Messages:     failures.messages,
+			Skipped:      failures.skipped,
+		}
+	} else {
+		return nil
+	}
+}
+
+// the outcome of visiting a single node
+type visitResult struct {
+	field         Field
+	failedChecks  []string
+	skippedChecks []string // see runCheck
+	messages      []string // a user-facing message for each failed check
+	children      []metaValue
+	err           error
+	// set if v failed a check or is below an alias
+	name    string
+	storage visitKey // where v is stored (see newStorageKey)
+	stored  bool     // false if v can't be addressed
+	alias   *aliasRoot
+}
+
+// runs v's checks and lists the nodes below it
+func visit(v metaValue) visitResult {
+	r := visitResult{}
+	if max := v.opts.limits.MaxDepth; max > 0 && len(v.Name)-1 > max {
+		r.err = newErrorLimitExceeded("MaxDepth", max, v)
+		return r
+	}
+	check, descend := v.opts.inMask(v)
+	var deferred []elemCheck
+	if check {
+		r.failedChecks, r.skippedChecks, deferred, r.err = runChecks(v)
+		if r.err != nil {
+			return r
+		}
+		if len(r.failedChecks) != 0 || len(r.skippedChecks) != 0 {
+			r.field = newField(v)
+		}
+		if len(r.failedChecks) != 0 {
+			if r.messages, r.err = failureMessages(v, r.field, r.failedChecks); r.err != nil {
+				return r
+			}
+		}
+	}
+	if len(r.failedChecks) != 0 || v.alias != nil {
+		r.name = joinName(v.Name)
+		r.storage, r.stored = newStorageKey(v.Value)
+		r.alias = v.alias
 	}
-	field2checks := make(map[Field][]string)
+	if descend {
+		if r.err = v.opts.checkElements(v); r.err != nil {
+			return r
+		}
+		r.children = v.children()
+		// a non-nil pointer's only child is the value it points to
+		if len(deferred) != 0 {
+			r.children[0].elemChecks = deferred
+		}
+	}
+	return r
+}
+
+// visits every node reachable from top in breadth first order
+func traverse(top metaValue) (*failureLog, error) {
+	failures := newFailureLog()
+	limits := top.opts.limits
 	q := newValueQueue()
-	q.Push(namedTop)
-	for q.Len() > 0 {
+	q.Push(top)
+	for nodes := 0; q.Len() > 0; nodes++ {
 		v := q.Pop()
-		failedChecks, err := runChecks(v)
-		if err != nil {
-			return err
+		if limits.MaxNodes > 0 && nodes == limits.MaxNodes {
+			return failures, newErrorLimitExceeded("MaxNodes", limits.MaxNodes, v)
+		}
+		r := visit(v)
+		if r.err != nil {
+			return failures, r.err
+		}
+		if err := failures.record(r, limits); err != nil {
+			return failures, err
+		}
+		for _, child := range r.children {
+			q.Push(child)
+		}
+	}
+	return failures, nil
+}
+
+// Visits the same nodes as traverse, one breadth first level at a time. Nodes within a level are visited concurrently, then their children are admitted to the next level in order, so the results (including which error is returned) match traverse.
+func traverseParallel(top metaValue, parallelism int) (*failureLog, error) {
+	failures := newFailureLog()
+	limits := top.opts.limits
+	q := newValueQueue()
+	level := []metaValue{q.admit(top)}
+	nodes := 0
+	for len(level) != 0 {
+		// nodes past MaxNodes aren't visited, but the ones before it may still fail first
+		var stoppedAt *metaValue
+		if limits.MaxNodes > 0 && nodes+len(level) > limits.MaxNodes {
+			stoppedAt = &level[limits.MaxNodes-nodes]
+			level = level[:limits.MaxNodes-nodes]
 		}
-		if len(failedChecks) != 0 {
-			field2checks[newField(v)] = failedChecks
+		nodes += len(level)
+		results := make([]visitResult, len(level))
+		next := int64(-1)
+		wg := sync.WaitGroup{}
+		for w := 0; w < parallelism && w < len(level); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := atomic.AddInt64(&next, 1); i < int64(len(level)); i = atomic.AddInt64(&next, 1) {
+					results[i] = visit(level[i])
+				}
+			}()
 		}
-		// push new nodes onto queue
-		switch v.Kind() {
-		case reflect.Ptr:
-			if !v.IsNil() {
-				q.Push(v.Indirect())
+		wg.Wait()
+		level = []metaValue{}
+		for _, r := range results {
+			if r.err != nil {
+				return failures, r.err
 			}
-		case reflect.Interface:
-			if !v.IsNil() {
-				q.Push(v.InterfaceValue())
+			if err := failures.record(r, limits); err != nil {
+				return failures, err
 			}
-		case reflect.Struct:
-			for j := 0; j < v.NumField(); j++ {
-				q.Push(v.Field(j))
+			for _, child := range r.children {
+				level = append(level, q.admit(child))
 			}
 		}
+		if stoppedAt != nil {
+			return failures, newErrorLimitExceeded("MaxNodes", limits.MaxNodes, *stoppedAt)
+		}
 	}
+	return failures, nil
+}
 
-	if len(field2checks) != 0 {
-		return ErrorChecksFailed{Field2Checks: field2checks}
-	} else {
+// the failures found by a traversal
+type failureLog struct {
+	checks   map[Field][]string
+	messages map[Field][]string
+	skipped  map[Field][]string
+	aliases  map[string][]string // the other names of failing values that were reached through more than one field, by the name they were first reported under
+	byName   map[string]*loggedFailure
+	byStore  map[visitKey]*loggedFailure // failures of values that can be addressed, by where they're stored
+}
+
+// the checks a value failed where it was first reported
+type loggedFailure struct {
+	name   string
+	checks []string
+}
+
+func newFailureLog() *failureLog {
+	return &failureLog{
+		checks:   make(map[Field][]string),
+		messages: make(map[Field][]string),
+		skipped:  make(map[Field][]string),
+		aliases:  make(map[string][]string),
+		byName:   make(map[string]*loggedFailure),
+		byStore:  make(map[visitKey]*loggedFailure),
+	}
+}
+
+// adds r's failures to the log, unless that would exceed MaxFailures. Below an alias, the checks that already failed where the value was first reached are left out, and r's name is listed as an alias instead.
+func (l *failureLog) record(r visitResult, limits Limits) error {
+	if len(r.skippedChecks) != 0 {
+		l.skipped[r.field] = append(l.skipped[r.field], r.skippedChecks...)
+	}
+	if r.alias != nil {
+		r = l.dropRepeated(r)
+	}
+	if len(r.failedChecks) == 0 {
+		return nil
+	}
+	// a pointer and the value it points to can have the same Field (e.g. when values are redacted), so their failures share an entry
+	checks, logged := l.checks[r.field]
+	if !logged && limits.MaxFailures > 0 && len(l.checks) == limits.MaxFailures {
+		return ErrorLimitExceeded{Limit: "MaxFailures", Max: limits.MaxFailures, Path: r.field.Path}
+	}
+	l.checks[r.field] = append(checks[:len(checks):len(checks)], r.failedChecks...)
+	l.messages[r.field] = append(l.messages[r.field][:len(checks):len(checks)], r.messages...)
+	if r.stored {
+		failure := l.byStore[r.storage]
+		if failure == nil {
+			failure = &loggedFailure{name: r.name}
+			l.byStore[r.storage] = failure
+		}
+		failure.checks = append(failure.checks, r.failedChecks...)
+		return nil
+	}
+	failure := l.byName[r.name]
+	if failure == nil {
+		failure = &loggedFailure{name: r.name}
+		l.byName[r.name] = failure
+	}
+	failure.checks = append(failure.checks, r.failedChecks...)
+	return nil
+}
+
+// removes the failures of r (a value below an alias) that were already reported where the value was first reached
+func (l *failureLog) dropRepeated(r visitResult) visitResult {
+	var first *loggedFailure
+	if r.stored {
+		first = l.byStore[r.storage]
+	} else if name, ok := r.alias.firstName(r.name); ok {
+		first = l.byName[name]
+	}
+	if first == nil || first.name == r.name {
+		return r
+	}
+	if !containsString(l.aliases[first.name], r.name) {
+		l.aliases[first.name] = append(l.aliases[first.name], r.name)
+	}
+	failedChecks := []string{}
+	messages := []string{}
+	for i, check := range r.failedChecks {
+		if !containsString(first.checks, check) {
+			failedChecks = append(failedChecks, check)
+			messages = append(messages, r.messages[i])
+		}
+	}
+	r.failedChecks = failedChecks
+	r.messages = messages
+	return r
+}
+
+// Lists the other names of each failing field that was reached through more than one field.
+func (l *failureLog) fieldAliases() map[Field][]string {
+	if len(l.aliases) == 0 {
 		return nil
 	}
+	field2aliases := make(map[Field][]string)
+	for field := range l.checks {
+		if names := l.aliases[field.Name]; len(names) != 0 {
+			list := append([]string{}, names...)
+			sort.Strings(list)
+			field2aliases[field] = list
+		}
+	}
+	return field2aliases
+}
+
+// true if the field named name is, or is below, the field named prefix
+func hasPathPrefix(name string, prefix string) bool {
+	return name == prefix || strings.HasPrefix(name, prefix+".") || strings.HasPrefix(name, prefix+"[")
 }
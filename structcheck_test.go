@@ -3,8 +3,11 @@ package structcheck
 import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type CycleNode struct {
@@ -55,7 +58,7 @@ func TestNilOneLayerDown(t *testing.T) {
 		},
 	})
 	assert.Error(t, err)
-	err = checkDeepEqual(map[Field][]string{Field{Name: "BigStruct.Slicy.NoNilly", Value: "[]interface {}(nil)", Number: "0.1"}: []string{"NotNil"}}, err.(ErrorChecksFailed).Field2Checks)
+	err = checkDeepEqual(map[Field][]string{Field{Name: "BigStruct.Slicy.NoNilly", Path: "Slicy.NoNilly", Value: "[]interface {}(nil)", Number: "0.1"}: []string{"NotNil"}}, err.(ErrorChecksFailed).Field2Checks)
 	assert.NoError(t, err)
 }
 
@@ -145,3 +148,725 @@ type notEqualError struct {
 func (e notEqualError) Error() string {
 	return fmt.Sprintf("Not equal: %#v != %#v", e.e, e.r)
 }
+
+func TestNilInterfaceField(t *testing.T) {
+	err := Validate(struct {
+		A interface{} `checks:"NotNil"`
+		B interface{} `checks:"Nil"`
+	}{})
+	assert.Error(t, err)
+	assert.Len(t, err.(ErrorChecksFailed).Field2Checks, 1)
+}
+
+func TestContainerElementsTraversed(t *testing.T) {
+	err := Validate(struct {
+		Slice []SlicyStruct
+		Map   map[string]*SlicyStruct
+	}{
+		Slice: []SlicyStruct{{NoNilly: []interface{}{}}, {}},
+		Map:   map[string]*SlicyStruct{"key": {}},
+	})
+	assert.Error(t, err)
+	err = checkDeepEqual(map[Field][]string{
+		Field{Name: "(anonymous struct).Slice[1].NoNilly", Path: "Slice[1].NoNilly", Value: "[]interface {}(nil)", Number: "0.1.1"}: []string{"NotNil"},
+		Field{Name: "(anonymous struct).Map[key].NoNilly", Path: "Map[key].NoNilly", Value: "[]interface {}(nil)", Number: "1.0.1"}: []string{"NotNil"},
+	}, err.(ErrorChecksFailed).Field2Checks)
+	assert.NoError(t, err)
+}
+
+func TestInterfaceCycle(t *testing.T) {
+	node := &struct{ Next interface{} }{}
+	node.Next = node
+	assert.NoError(t, Validate(node))
+}
+
+type GroupyStruct struct {
+	ID   *int   `checks:"Nil@create,NotNil@update|sync"`
+	Name string `checks:"NotEmpty"`
+}
+
+func TestGroupsCreate(t *testing.T) {
+	id := 1
+	err := Validate(GroupyStruct{ID: &id}, WithGroups("create"))
+	assert.Error(t, err)
+	assert.Equal(t, []string{"create"}, err.(ErrorChecksFailed).Groups)
+	assert.Len(t, err.(ErrorChecksFailed).Field2Checks, 2)
+}
+
+func TestGroupsUpdate(t *testing.T) {
+	assert.NoError(t, Validate(GroupyStruct{ID: new(int), Name: "a"}, WithGroups("sync")))
+	err := Validate(GroupyStruct{Name: "a"}, WithGroups("update"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "(groups: update)")
+}
+
+func TestGroupsDefaultOnly(t *testing.T) {
+	id := 1
+	assert.NoError(t, Validate(GroupyStruct{ID: &id, Name: "a"}))
+	assert.NoError(t, Validate(GroupyStruct{Name: "a"}))
+}
+
+type PatchyStruct struct {
+	ID      *int   `checks:"NotNil"`
+	Name    string `checks:"NotEmpty"`
+	Address *struct {
+		Zip    string `checks:"NotEmpty"`
+		Street string `checks:"NotEmpty"`
+	} `checks:"NotNil"`
+	Tags []struct {
+		Name string `checks:"NotEmpty"`
+	}
+}
+
+func TestFieldMask(t *testing.T) {
+	patch := PatchyStruct{}
+	patch.Address = &struct {
+		Zip    string `checks:"NotEmpty"`
+		Street string `checks:"NotEmpty"`
+	}{Street: "Main St"}
+	patch.Tags = append(patch.Tags, struct {
+		Name string `checks:"NotEmpty"`
+	}{})
+	assert.NoError(t, Validate(patch, WithFieldMask("Address.Street")))
+	err := Validate(patch, WithFieldMask("Address", "Tags[*].Name"))
+	assert.Error(t, err)
+	names := []string{}
+	for field := range err.(ErrorChecksFailed).Field2Checks {
+		names = append(names, field.Name)
+	}
+	assert.ElementsMatch(t, []string{"PatchyStruct.Address.Zip", "PatchyStruct.Tags[0].Name"}, names)
+}
+
+func TestFieldMaskUnknownField(t *testing.T) {
+	err := Validate(PatchyStruct{}, WithFieldMask("Address.Zip", "Address.Postcode"))
+	assert.IsType(t, ErrorUnknownFields{}, err)
+	assert.Equal(t, []string{"Address.Postcode"}, err.(ErrorUnknownFields).Fields)
+}
+
+type Order struct {
+	Total int `checks:"Positive"`
+}
+
+func TestTopLevelSliceOfStructs(t *testing.T) {
+	assert.NoError(t, Validate([]Order{{Total: 1}}))
+	err := Validate([]*Order{{Total: 1}, {Total: 0}})
+	assert.Error(t, err)
+	err = checkDeepEqual(map[Field][]string{Field{Name: "[]*Order[1].Total", Path: "[1].Total", Value: "0", Number: "1.0"}: []string{"Positive"}}, err.(ErrorChecksFailed).Field2Checks)
+	assert.NoError(t, err)
+}
+
+func TestTopLevelMapOfStructs(t *testing.T) {
+	err := Validate(&map[string]Order{"a": {Total: 1}, "b": {}})
+	assert.Error(t, err)
+	for field := range err.(ErrorChecksFailed).Field2Checks {
+		assert.Equal(t, "map[string]Order[b].Total", field.Name)
+	}
+}
+
+func TestNoDescendIntoElements(t *testing.T) {
+	type Cart struct {
+		Checked []Order
+		Opaque  []Order `checks:"NotEmpty,nodescend"`
+	}
+	err := Validate(Cart{Checked: []Order{{}}, Opaque: []Order{{}}})
+	require.Error(t, err)
+	var names []string
+	for field := range err.(ErrorChecksFailed).Field2Checks {
+		names = append(names, field.Name)
+	}
+	assert.Equal(t, []string{"Cart.Checked[0].Total"}, names)
+}
+
+func TestMapOfStructsNaNKeys(t *testing.T) {
+	m := map[float64]Order{math.NaN(): {}, 1: {Total: 1}}
+	err := Validate(m)
+	require.Error(t, err)
+	var names []string
+	for field := range err.(ErrorChecksFailed).Field2Checks {
+		names = append(names, field.Name)
+	}
+	assert.Equal(t, []string{"map[float64]Order[NaN].Total"}, names)
+}
+
+func TestRootChecks(t *testing.T) {
+	assert.NoError(t, Validate([]Order{{Total: 1}}, WithRootChecks("NotEmpty", "MaxLen(1)")))
+	err := Validate([]Order{}, WithRootChecks("NotEmpty", "MaxLen(1)"))
+	assert.Error(t, err)
+	assert.Equal(t, []string{"NotEmpty"}, err.(ErrorChecksFailed).Field2Checks[Field{Name: "[]Order", Value: "[]structcheck.Order{}", Number: ""}])
+	err = Validate([]Order{{Total: 1}, {Total: 1}}, WithRootChecks("MaxLen(1)"))
+	assert.Error(t, err)
+	assert.IsType(t, ErrorIllegalCheck{}, Validate([]Order{}, WithRootChecks("MaxLen(x)")))
+	assert.IsType(t, ErrorIllegalCheck{}, Validate([]Order{}, WithRootChecks("NotAThing")))
+}
+
+func TestRootChecksFromCheckSet(t *testing.T) {
+	checkSet := map[string]Check{
+		"Positive": DefaultChecks["Positive"],
+		"Pair": func(v reflect.Value) bool {
+			return v.Len() == 2
+		},
+	}
+	orders := []Order{{Total: 1}}
+	err := CustomValidate(orders, BuildTagCheckFinder(checkSet), WithRootChecks("Pair", "MaxLen(1)"))
+	require.Equal(t, map[string][]string{"[]Order": {"Pair"}}, failuresByName(t, err))
+	stringy, err := BuildStringyCheckFinder(map[string][]string{"[*].Total": {"Positive"}}, checkSet)
+	require.NoError(t, err)
+	err = CustomValidate(orders, stringy, WithRootChecks("Pair"))
+	require.Equal(t, map[string][]string{"[]Order": {"Pair"}}, failuresByName(t, err))
+	// names are resolved against the finder's check set, not DefaultChecks
+	require.IsType(t, ErrorIllegalCheck{}, Validate(orders, WithRootChecks("Pair")))
+	require.IsType(t, ErrorIllegalCheck{}, CustomValidate(orders, BuildTagCheckFinder(checkSet), WithRootChecks("NotEmpty")))
+}
+
+func TestParamChecksInTags(t *testing.T) {
+	err := Validate(struct {
+		Name string `checks:"MinLen(2),MaxLen(4)"`
+	}{Name: "abcde"})
+	assert.Error(t, err)
+	err = Validate(struct {
+		Name string `checks:"MinLen(two)"`
+	}{})
+	assert.IsType(t, ErrorIllegalCheck{}, err)
+}
+
+func TestIllegalCheckName(t *testing.T) {
+	type Line struct {
+		X string `checks:"MinLen(two)"`
+	}
+	err := Validate(struct{ L []Line }{L: []Line{{}}})
+	require.IsType(t, ErrorIllegalCheck{}, err)
+	assert.Contains(t, err.Error(), "(anonymous struct).L[0].X: ")
+}
+
+type Catalog struct {
+	Name     string `checks:"NotEmpty"`
+	Products []*Product
+	Featured *Product `checks:"NotNil"`
+}
+
+type Product struct {
+	SKU      string `checks:"NotEmpty"`
+	Price    int    `checks:"Positive"`
+	Variants []Product
+	Catalog  *Catalog
+}
+
+func buildCatalog(n int) *Catalog {
+	c := &Catalog{}
+	for i := 0; i < n; i++ {
+		p := &Product{SKU: fmt.Sprint(i), Price: i % 7, Catalog: c}
+		for j := 0; j < 3; j++ {
+			p.Variants = append(p.Variants, Product{Price: j})
+		}
+		c.Products = append(c.Products, p)
+	}
+	return c
+}
+
+func TestParallelMatchesSequential(t *testing.T) {
+	c := buildCatalog(200)
+	c.Featured = c.Products[3]
+	seq := Validate(c)
+	require.Error(t, seq)
+	for _, n := range []int{2, 8, 64} {
+		par := Validate(c, WithParallelism(n))
+		require.Error(t, par)
+		assert.Equal(t, seq.(ErrorChecksFailed).Field2Checks, par.(ErrorChecksFailed).Field2Checks)
+		assert.Equal(t, seq.Error(), par.Error())
+	}
+}
+
+func TestParallelValid(t *testing.T) {
+	c := &Catalog{Name: "x"}
+	c.Featured = &Product{SKU: "a", Price: 1, Catalog: c}
+	c.Products = []*Product{c.Featured}
+	assert.NoError(t, Validate(c, WithParallelism(4)))
+}
+
+func TestParallelFirstErrorInOrder(t *testing.T) {
+	s := struct {
+		A []struct {
+			X string `checks:"BadA"`
+		}
+		B struct {
+			Y string `checks:"BadB"`
+		}
+	}{}
+	s.A = make([]struct {
+		X string `checks:"BadA"`
+	}, 10)
+	seq := Validate(s)
+	require.IsType(t, ErrorIllegalCheck{}, seq)
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, seq.Error(), Validate(s, WithParallelism(4)).Error())
+	}
+}
+
+type pointerLevels struct {
+	Optional *int   `checks:"Positive"`
+	Strict   *int   `checks:"elem:Positive"`
+	Both     *int   `checks:"NotNil,Positive"`
+	Pointer  *[]int `checks:"ptr:NotNil,elem:NotNil"`
+	Twice    **int  `checks:"elem:Positive"`
+}
+
+func TestPointerLevelsNil(t *testing.T) {
+	err := Validate(pointerLevels{})
+	require.Equal(t, map[string][]string{
+		"pointerLevels.Strict":  {"elem:Positive"},
+		"pointerLevels.Both":    {"NotNil"},
+		"pointerLevels.Pointer": {"ptr:NotNil", "elem:NotNil"},
+		"pointerLevels.Twice":   {"elem:Positive"},
+	}, failuresByName(t, err))
+	// messages don't repeat the prefix
+	require.Contains(t, err.(ErrorChecksFailed).UserMessages(), "Strict: must be positive")
+}
+
+func TestPointerLevelsSet(t *testing.T) {
+	zeros := make([]int, 4)
+	one := 1
+	zeroPtr := &zeros[3]
+	err := Validate(pointerLevels{
+		Optional: &zeros[0],
+		Strict:   &zeros[1],
+		Both:     &zeros[2],
+		Pointer:  &[]int{},
+		Twice:    &zeroPtr,
+	})
+	require.IsType(t, ErrorChecksFailed{}, err)
+	// elem checks are reported once, with the value they looked at
+	for field := range err.(ErrorChecksFailed).Field2Checks {
+		require.Equal(t, "0", field.Value, field.Name)
+	}
+	require.Equal(t, map[string][]string{
+		"pointerLevels.Both":     {"Positive"},
+		"pointerLevels.Optional": {"Positive"},
+		"pointerLevels.Strict":   {"elem:Positive"},
+		"pointerLevels.Twice":    {"elem:Positive"},
+	}, failuresByName(t, err))
+
+	onePtr := &one
+	two := 2
+	require.NoError(t, Validate(pointerLevels{
+		Strict:  &one,
+		Both:    &two,
+		Pointer: &[]int{},
+		Twice:   &onePtr,
+	}))
+}
+
+func TestPointerLevelsUnknownCheck(t *testing.T) {
+	err := Validate(struct {
+		A *int `checks:"elem:NotAThing"`
+	}{})
+	require.IsType(t, ErrorIllegalCheck{}, err)
+}
+
+type sharedChild struct {
+	ID int `checks:"Positive"`
+}
+
+type sharedParent struct {
+	A     *sharedChild
+	B     *sharedChild `checks:"Nil"`
+	C     *sharedChild `checks:"elem:Positive"`
+	Items []*sharedChild
+	Self  *sharedParent
+}
+
+func TestSharedPointerChecks(t *testing.T) {
+	child := &sharedChild{}
+	p := &sharedParent{A: child, B: child, C: child, Items: []*sharedChild{child}}
+	p.Self = p
+	err := Validate(p)
+	// B's own checks run even though A reached the child first
+	require.Equal(t, map[string][]string{
+		"sharedParent.A.ID": {"Positive"},
+		"sharedParent.B":    {"Nil"},
+	}, failuresByName(t, err))
+	aliases := err.(ErrorChecksFailed).Aliases
+	require.Len(t, aliases, 1)
+	for field, names := range aliases {
+		require.Equal(t, "sharedParent.A.ID", field.Name)
+		require.Equal(t, []string{"sharedParent.B.ID", "sharedParent.C.ID", "sharedParent.Items[0].ID"}, names)
+	}
+	require.Contains(t, err.Error(), "(also sharedParent.B.ID, sharedParent.C.ID, sharedParent.Items[0].ID)")
+}
+
+func TestSharedPointerElemChecks(t *testing.T) {
+	child := &sharedChild{ID: 1}
+	zero := &sharedChild{}
+	err := Validate(sharedParent{A: child, C: child, Items: []*sharedChild{zero, zero}})
+	require.Equal(t, map[string][]string{
+		"sharedParent.Items[0].ID": {"Positive"},
+	}, failuresByName(t, err))
+	require.Equal(t, []string{"sharedParent.Items[1].ID"}, err.(ErrorChecksFailed).Aliases[Field{Name: "sharedParent.Items[0].ID", Path: "Items[0].ID", Value: "0", Number: "3.0.0"}])
+}
+
+func TestSharedPointerSecondPathChecks(t *testing.T) {
+	finder, err := BuildStringyCheckFinder(map[string][]string{"B.ID": {"Positive"}}, DefaultChecks)
+	require.NoError(t, err)
+	child := &sharedChild{}
+	for _, parallelism := range []int{1, 4} {
+		// only the field that reached the child second has a failing check
+		err = CustomValidate(sharedParent{A: child, B: child}, finder, WithParallelism(parallelism))
+		require.Equal(t, map[string][]string{
+			"sharedParent.B.ID": {"Positive"},
+		}, failuresByName(t, err))
+		require.Empty(t, err.(ErrorChecksFailed).Aliases)
+	}
+}
+
+type diamondNode struct {
+	ID   int `checks:"Positive"`
+	A, B *diamondNode
+}
+
+func TestSharedPointerDiamond(t *testing.T) {
+	// every node is reached through both fields of the node above it, so there are 2^40 paths to the bottom
+	bottom := &diamondNode{}
+	node := bottom
+	for i := 0; i < 40; i++ {
+		node = &diamondNode{ID: 1, A: node, B: node}
+	}
+	start := time.Now()
+	for _, parallelism := range []int{1, 4} {
+		err := Validate(node, WithParallelism(parallelism))
+		require.Len(t, err.(ErrorChecksFailed).Field2Checks, 1)
+	}
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+type sharedMaps struct {
+	A map[string]sharedChild
+	B map[string]sharedChild
+}
+
+func TestSharedMapChecks(t *testing.T) {
+	m := map[string]sharedChild{"k": {}}
+	err := Validate(sharedMaps{A: m, B: m})
+	require.Equal(t, map[string][]string{
+		"sharedMaps.A[k].ID": {"Positive"},
+	}, failuresByName(t, err))
+	require.Contains(t, err.Error(), "(also sharedMaps.B[k].ID)")
+}
+
+func TestExclusiveOwnership(t *testing.T) {
+	child := &sharedChild{ID: 1}
+	p := &sharedParent{A: child, C: &sharedChild{ID: 1}, Items: []*sharedChild{child}}
+	p.Self = p
+	require.NoError(t, Validate(p))
+	err := Validate(p, WithExclusiveOwnership(reflect.TypeOf(child), reflect.TypeOf(p)))
+	// the cycle through Self isn't sharing
+	require.Equal(t, map[string][]string{
+		"sharedParent.Items[0]": {"Exclusive"},
+	}, failuresByName(t, err))
+}
+
+type firstFieldInner struct {
+	N int `checks:"Positive"`
+}
+
+type firstFieldOuter struct {
+	Inner firstFieldInner `checks:"Zero"`
+	Other int
+}
+
+func TestFirstFieldPointer(t *testing.T) {
+	outer := &firstFieldOuter{Other: 1}
+	err := Validate(struct {
+		Outer *firstFieldOuter
+		Inner *firstFieldInner
+	}{outer, &outer.Inner})
+	// &outer and &outer.Inner share an address but are different values
+	require.Equal(t, map[string][]string{
+		"(anonymous struct).Outer.Inner.N": {"Positive"},
+		"(anonymous struct).Inner.N":       {"Positive"},
+	}, failuresByName(t, err))
+	require.Empty(t, err.(ErrorChecksFailed).Aliases)
+}
+
+type embeddedMiddle struct {
+	firstFieldInner
+	Name string `checks:"NotEmpty"`
+}
+
+type embeddedOuter struct {
+	*embeddedMiddle
+	Inner *firstFieldInner
+}
+
+func TestEmbeddedPointerStruct(t *testing.T) {
+	middle := &embeddedMiddle{Name: "m"}
+	err := Validate(embeddedOuter{embeddedMiddle: middle, Inner: &middle.firstFieldInner})
+	require.Equal(t, map[string][]string{
+		"embeddedOuter.N":       {"Positive"},
+		"embeddedOuter.Inner.N": {"Positive"},
+	}, failuresByName(t, err))
+}
+
+type selfSlice []interface{}
+
+func TestSelfReferencingSlice(t *testing.T) {
+	s := selfSlice{nil, -1}
+	s[0] = s
+	m := map[string]interface{}{"n": -1}
+	m["self"] = m
+	positive, err := BuildFixedCheckFinder([]string{"Positive"}, DefaultChecks)
+	require.NoError(t, err)
+	err = CustomValidate(struct {
+		S    selfSlice
+		Also selfSlice
+		M    map[string]interface{}
+	}{s, s, m}, positive)
+	require.Equal(t, map[string][]string{
+		"(anonymous struct).S[1].(int)": {"Positive"},
+		"(anonymous struct).M[n].(int)": {"Positive"},
+	}, failuresByName(t, err))
+	require.Equal(t, []string{"(anonymous struct).Also[1].(int)"}, err.(ErrorChecksFailed).Aliases[Field{Name: "(anonymous struct).S[1].(int)", Path: "S[1].(int)", Value: "-1", Number: "0.1"}])
+}
+
+type unexportedStruct struct {
+	Public  int `checks:"Positive"`
+	private int `checks:"Positive,Stringy"`
+	hidden  *unexportedStruct
+	firstFieldInner
+}
+
+var unexportedChecks = map[string]Check{
+	"Positive": DefaultChecks["Positive"],
+	"Stringy": func(v reflect.Value) bool {
+		_, ok := v.Interface().(string)
+		return ok
+	},
+}
+
+func TestUnexportedTraverse(t *testing.T) {
+	s := unexportedStruct{hidden: &unexportedStruct{Public: 1, private: 1}}
+	err := CustomValidate(s, BuildTagCheckFinder(unexportedChecks), WithInterfaceChecks("Stringy"))
+	// Stringy needs Interface() and is skipped on unexported fields
+	require.Equal(t, map[string][]string{
+		"unexportedStruct.Public":   {"Positive"},
+		"unexportedStruct.private":  {"Positive"},
+		"unexportedStruct.N":        {"Positive"},
+		"unexportedStruct.hidden.N": {"Positive"},
+	}, failuresByName(t, err))
+	skipped := map[string][]string{}
+	for field, checks := range err.(ErrorChecksFailed).Skipped {
+		skipped[field.Name] = checks
+	}
+	require.Equal(t, map[string][]string{
+		"unexportedStruct.private":        {"Stringy"},
+		"unexportedStruct.hidden.private": {"Stringy"},
+	}, skipped)
+}
+
+func TestUnexportedCheckPanics(t *testing.T) {
+	checks := map[string]Check{
+		"Positive": DefaultChecks["Positive"],
+		"Stringy": func(v reflect.Value) bool {
+			panic("not a string")
+		},
+	}
+	// panics from checks aren't recovered
+	require.PanicsWithValue(t, "not a string", func() {
+		_ = CustomValidate(unexportedStruct{Public: 1, private: 1}, BuildTagCheckFinder(checks))
+	})
+	// including the ones from undeclared checks that need Interface()
+	require.Panics(t, func() {
+		_ = CustomValidate(unexportedStruct{Public: 1, private: 1}, BuildTagCheckFinder(unexportedChecks))
+	})
+	require.NotPanics(t, func() {
+		_ = CustomValidate(unexportedStruct{Public: 1, private: 1}, BuildTagCheckFinder(unexportedChecks), WithInterfaceChecks("Stringy"))
+	})
+}
+
+func TestUnexportedSkip(t *testing.T) {
+	s := unexportedStruct{hidden: &unexportedStruct{}}
+	err := CustomValidate(s, BuildTagCheckFinder(unexportedChecks), WithUnexportedPolicy(UnexportedSkip))
+	// embedded structs are still traversed
+	require.Equal(t, map[string][]string{
+		"unexportedStruct.Public": {"Positive"},
+		"unexportedStruct.N":      {"Positive"},
+	}, failuresByName(t, err))
+}
+
+func TestUnexportedIllegal(t *testing.T) {
+	err := CustomValidate(unexportedStruct{}, BuildTagCheckFinder(unexportedChecks), WithUnexportedPolicy(UnexportedIllegal))
+	require.IsType(t, ErrorIllegalCheck{}, err)
+	require.Contains(t, err.Error(), "unexportedStruct.private")
+	require.NoError(t, Validate(struct {
+		Public  int `checks:"Positive"`
+		private int
+	}{Public: 1}, WithUnexportedPolicy(UnexportedIllegal)))
+}
+
+type embedBase struct {
+	ID    int `checks:"Positive"`
+	Name  string
+	Owner *embedOwner
+}
+
+type embedOwner struct {
+	Email string `checks:"NotEmpty"`
+}
+
+type embedAudit struct {
+	Name string `checks:"NotEmpty"`
+}
+
+type embedRecord struct {
+	*embedBase
+	embedAudit
+	fmt.Stringer
+	Name string `checks:"NotEmpty"`
+}
+
+func TestEmbeddedFieldsPromoted(t *testing.T) {
+	err := Validate(embedRecord{embedBase: &embedBase{Owner: &embedOwner{}}})
+	// embedBase.Name and embedAudit.Name are hidden by embedRecord.Name
+	require.Equal(t, map[string][]string{
+		"embedRecord.ID":              {"Positive"},
+		"embedRecord.Owner.Email":     {"NotEmpty"},
+		"embedRecord.embedAudit.Name": {"NotEmpty"},
+		"embedRecord.Name":            {"NotEmpty"},
+	}, failuresByName(t, err))
+}
+
+func TestEmbeddedSharedPointerAliases(t *testing.T) {
+	base := &embedBase{Owner: &embedOwner{Email: "a"}}
+	err := Validate(struct {
+		First  embedRecord
+		Second embedRecord
+		Named  *embedBase
+	}{First: embedRecord{embedBase: base}, Second: embedRecord{embedBase: base}, Named: base})
+	// promoted names aren't extensions of the embedded pointer's name
+	require.Equal(t, map[string][]string{
+		"(anonymous struct).Named.ID":               {"Positive"},
+		"(anonymous struct).First.Name":             {"NotEmpty"},
+		"(anonymous struct).First.embedAudit.Name":  {"NotEmpty"},
+		"(anonymous struct).Second.Name":            {"NotEmpty"},
+		"(anonymous struct).Second.embedAudit.Name": {"NotEmpty"},
+	}, failuresByName(t, err))
+	require.Contains(t, err.Error(), "(also (anonymous struct).First.ID, (anonymous struct).Second.ID)")
+}
+
+func TestEmbeddedTypeNames(t *testing.T) {
+	err := Validate(embedRecord{embedBase: &embedBase{Owner: &embedOwner{}}}, WithEmbeddedTypeNames())
+	require.Equal(t, map[string][]string{
+		"embedRecord.embedBase.ID":          {"Positive"},
+		"embedRecord.embedBase.Owner.Email": {"NotEmpty"},
+		"embedRecord.embedAudit.Name":       {"NotEmpty"},
+		"embedRecord.Name":                  {"NotEmpty"},
+	}, failuresByName(t, err))
+}
+
+func TestEmbeddedInterface(t *testing.T) {
+	// fields aren't promoted through interfaces
+	err := Validate(embedRecord{Stringer: sharedTestStringer{}, embedBase: &embedBase{ID: 1}, embedAudit: embedAudit{"a"}, Name: "b"})
+	require.Equal(t, map[string][]string{
+		"embedRecord.Stringer.(sharedTestStringer).Value": {"NotEmpty"},
+	}, failuresByName(t, err))
+}
+
+type sharedTestStringer struct {
+	Value string `checks:"NotEmpty"`
+}
+
+func (s sharedTestStringer) String() string {
+	return s.Value
+}
+
+func TestEmbeddedFieldPatterns(t *testing.T) {
+	finder, err := BuildTypedStringyCheckFinder(reflect.TypeOf(embedRecord{}), map[string][]string{
+		"ID":              {"Negative"},
+		"embedAudit.Name": {"Empty"},
+		"Owner":           {"NotNil"},
+	}, DefaultChecks)
+	require.NoError(t, err)
+	err = CustomValidate(embedRecord{embedBase: &embedBase{ID: 1}, embedAudit: embedAudit{"a"}}, finder)
+	require.Equal(t, map[string][]string{
+		"embedRecord.ID":              {"Negative"},
+		"embedRecord.Owner":           {"NotNil"},
+		"embedRecord.embedAudit.Name": {"Empty"},
+	}, failuresByName(t, err))
+
+	require.NoError(t, Validate(embedRecord{}, WithFieldMask("ID", "Owner.Email")))
+	require.IsType(t, ErrorUnknownFields{}, Validate(embedRecord{}, WithFieldMask("Email")))
+	require.NoError(t, Validate(embedRecord{}, WithFieldMask("embedBase.Owner"), WithEmbeddedTypeNames()))
+	require.IsType(t, ErrorUnknownFields{}, Validate(embedRecord{}, WithFieldMask("Owner"), WithEmbeddedTypeNames()))
+}
+
+type directiveBlob struct {
+	Data []int `checks:"NotEmpty"`
+}
+
+type directiveStruct struct {
+	Ignored  *directiveBlob `checks:"-"`
+	Shallow  *directiveBlob `checks:"NotNil,nodescend"`
+	Untagged directiveBlob  `checks:"notagsbelow"`
+	Checked  directiveBlob
+}
+
+func TestTagDirectives(t *testing.T) {
+	err := Validate(directiveStruct{Shallow: &directiveBlob{}})
+	require.Equal(t, map[string][]string{
+		"directiveStruct.Checked.Data": {"NotEmpty"},
+	}, failuresByName(t, err))
+	err = Validate(directiveStruct{})
+	require.Equal(t, map[string][]string{
+		"directiveStruct.Shallow":      {"NotNil"},
+		"directiveStruct.Checked.Data": {"NotEmpty"},
+	}, failuresByName(t, err))
+}
+
+func TestTagDirectivesWithOtherFinders(t *testing.T) {
+	fixed, err := BuildFixedCheckFinder([]string{"NotNil"}, DefaultChecks)
+	require.NoError(t, err)
+	err = CustomValidate(directiveStruct{Shallow: &directiveBlob{}}, fixed)
+	// fixed checks still apply below notagsbelow, but not to skipped fields or below nodescend
+	require.Equal(t, map[string][]string{
+		"directiveStruct.Untagged.Data": {"NotNil"},
+		"directiveStruct.Checked.Data":  {"NotNil"},
+	}, failuresByName(t, err))
+}
+
+type limitNode struct {
+	Name string `checks:"NotEmpty"`
+	Next *limitNode
+}
+
+func TestLimits(t *testing.T) {
+	c := buildCatalog(20)
+	for _, tc := range []struct {
+		limits Limits
+		limit  string
+		path   string
+	}{
+		{Limits{MaxElements: 10}, "MaxElements", "Products"},
+		{Limits{MaxNodes: 30}, "MaxNodes", "Products[6]"},
+		{Limits{MaxFailures: 3}, "MaxFailures", "Products[7].Price"},
+	} {
+		for _, n := range []int{1, 4} {
+			err := Validate(c, WithLimits(tc.limits), WithParallelism(n))
+			require.IsType(t, ErrorLimitExceeded{}, err)
+			e := err.(ErrorLimitExceeded)
+			assert.Equal(t, tc.limit, e.Limit)
+			assert.Equal(t, tc.path, e.Path)
+		}
+	}
+	err := Validate(c, WithLimits(Limits{MaxFailures: 3}))
+	assert.Len(t, err.(ErrorLimitExceeded).Field2Checks, 3)
+	assert.IsType(t, ErrorChecksFailed{}, Validate(c, WithLimits(Limits{MaxDepth: 5, MaxElements: 20})))
+}
+
+func TestMaxDepth(t *testing.T) {
+	list := &limitNode{Name: "a"}
+	for i := 0; i < 10; i++ {
+		list = &limitNode{Name: "a", Next: list}
+	}
+	err := Validate(list, WithLimits(Limits{MaxDepth: 3}))
+	require.IsType(t, ErrorLimitExceeded{}, err)
+	assert.Equal(t, "Validation stopped at Next.Next.Next.Name: MaxDepth of 3 exceeded", err.Error())
+	assert.NoError(t, Validate(list, WithLimits(Limits{MaxDepth: 11})))
+}
@@ -0,0 +1,78 @@
+package structcheck
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Associates checks with types. Registered checks run wherever the traversal reaches a value of the type, in addition to the checks found by the CheckFinder.
+type TypeRegistry struct {
+	mu         sync.RWMutex
+	byType     map[reflect.Type][]namedCheck
+	interfaces []reflect.Type
+}
+
+type namedCheck struct {
+	name           string
+	check          Check
+	needsInterface bool // the check was registered for an interface type, so it has to convert values to it
+}
+
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		byType: make(map[reflect.Type][]namedCheck),
+	}
+}
+
+// type checks applied by Validate and CustomValidate, unless WithTypeRegistry selects another registry
+var DefaultTypeChecks = NewTypeRegistry()
+
+// Registers a check for every value of type t. If t is an interface type, the check runs on every value implementing it.
+func (r *TypeRegistry) Register(t reflect.Type, checkName string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.byType[t]; !ok && t.Kind() == reflect.Interface {
+		r.interfaces = append(r.interfaces, t)
+	}
+	r.byType[t] = append(r.byType[t], namedCheck{name: checkName, check: check, needsInterface: t.Kind() == reflect.Interface})
+}
+
+// Registers the named checks from DefaultChecks for every value of type t in DefaultTypeChecks
+func RegisterTypeChecks(t reflect.Type, checkNames ...string) error {
+	return DefaultTypeChecks.RegisterChecks(t, checkNames...)
+}
+
+// Registers the named checks from DefaultChecks for every value of type t
+func (r *TypeRegistry) RegisterChecks(t reflect.Type, checkNames ...string) error {
+	for _, checkName := range checkNames {
+		if _, ok := DefaultChecks[checkName]; !ok {
+			return fmt.Errorf("No check found with name: %v", checkName)
+		}
+	}
+	for _, checkName := range checkNames {
+		r.Register(t, checkName, DefaultChecks[checkName])
+	}
+	return nil
+}
+
+// returns the checks registered for v's type and the interfaces it implements. Values implementing an interface through a non-nil pointer are checked at the pointee instead when the pointee also implements it.
+func (r *TypeRegistry) checksFor(v reflect.Value) []namedCheck {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t := v.Type()
+	checks := r.byType[t]
+	for _, iface := range r.interfaces {
+		if !t.Implements(iface) {
+			continue
+		}
+		if t.Kind() == reflect.Ptr && !v.IsNil() && t.Elem().Implements(iface) {
+			continue
+		}
+		checks = append(checks[:len(checks):len(checks)], r.byType[iface]...)
+	}
+	return checks
+}
@@ -0,0 +1,83 @@
+package structcheck
+
+import (
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"testing"
+)
+
+type typeTestUUID [16]byte
+
+type typeTestIdentifier interface {
+	Identifier() string
+}
+
+type typeTestThing struct {
+	Name string
+}
+
+func (t *typeTestThing) Identifier() string {
+	return t.Name
+}
+
+type typeTestStruct struct {
+	ID      typeTestUUID
+	OtherID *typeTestUUID
+	Tagged  typeTestUUID `checks:"NotZero"`
+	Things  []*typeTestThing
+	Thing   typeTestIdentifier
+}
+
+func newTypeTestRegistry(t *testing.T) *TypeRegistry {
+	r := NewTypeRegistry()
+	require.NoError(t, r.RegisterChecks(reflect.TypeOf(typeTestUUID{}), "NotZero"))
+	r.Register(reflect.TypeOf((*typeTestIdentifier)(nil)).Elem(), "HasIdentifier", func(v reflect.Value) bool {
+		return v.IsNil() || v.Interface().(typeTestIdentifier).Identifier() != ""
+	})
+	return r
+}
+
+func TestTypeChecks_good(t *testing.T) {
+	require.NoError(t, Validate(typeTestStruct{
+		ID:     typeTestUUID{1},
+		Tagged: typeTestUUID{1},
+		Things: []*typeTestThing{{Name: "a"}, nil},
+	}, WithTypeRegistry(newTypeTestRegistry(t))))
+}
+
+func TestTypeChecks_bad(t *testing.T) {
+	bad := typeTestStruct{
+		OtherID: &typeTestUUID{},
+		Things:  []*typeTestThing{{Name: "a"}, {}},
+	}
+	err := Validate(bad, WithTypeRegistry(newTypeTestRegistry(t)))
+	require.Equal(t, map[string][]string{
+		"typeTestStruct.ID":        {"NotZero"},
+		"typeTestStruct.OtherID":   {"NotZero"},
+		"typeTestStruct.Tagged":    {"NotZero"},
+		"typeTestStruct.Things[1]": {"HasIdentifier"},
+	}, failuresByName(t, err))
+	// DefaultTypeChecks doesn't have the test's checks
+	require.Equal(t, map[string][]string{
+		"typeTestStruct.Tagged": {"NotZero"},
+	}, failuresByName(t, Validate(bad)))
+}
+
+func TestTypeChecks_unexported(t *testing.T) {
+	calls := 0
+	r := NewTypeRegistry()
+	r.Register(reflect.TypeOf((*typeTestIdentifier)(nil)).Elem(), "HasIdentifier", func(v reflect.Value) bool {
+		calls++
+		return false
+	})
+	err := Validate(struct {
+		thing *typeTestThing
+	}{&typeTestThing{}}, WithTypeRegistry(r))
+	// checks registered for interfaces need Interface(), which values read from unexported fields don't allow
+	require.NoError(t, err)
+	require.Zero(t, calls)
+}
+
+func TestRegisterTypeChecks_bad(t *testing.T) {
+	require.Error(t, RegisterTypeChecks(reflect.TypeOf(typeTestUUID{}), "NotAThing"))
+}
@@ -0,0 +1,53 @@
+package structcheck
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const untypedTestDocument = `{
+	"name": "",
+	"count": -1,
+	"owner": null,
+	"items": [{"id": 1}, {"id": 0, "tags": []}],
+	"weird.key": {"id": 0}
+}`
+
+func TestWithUntyped(t *testing.T) {
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(untypedTestDocument), &doc))
+	finder, err := BuildStringyCheckFinder(map[string][]string{
+		"name":          {"NotEmpty"},
+		"count":         {"Positive"},
+		"owner":         {"NotNil"},
+		"items[*].id":   {"Positive"},
+		"items[*].tags": {"NotEmpty"},
+		"**.(string)":   {"NotEmpty"},
+	}, DefaultChecks)
+	require.NoError(t, err)
+	err = CustomValidate(doc, finder, WithUntyped())
+	require.Equal(t, map[string][]string{
+		"(document).name":          {"NotEmpty"},
+		"(document).count":         {"Positive"},
+		"(document).owner":         {"NotNil"},
+		"(document).items[1].id":   {"Positive"},
+		"(document).items[1].tags": {"NotEmpty"},
+	}, failuresByName(t, err))
+}
+
+func TestWithUntyped_keysNeedingBrackets(t *testing.T) {
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(untypedTestDocument), &doc))
+	finder, err := BuildStringyCheckFinder(map[string][]string{"[weird.key].id": {"Positive"}}, DefaultChecks)
+	require.NoError(t, err)
+	err = CustomValidate(doc, finder, WithUntyped())
+	require.Equal(t, map[string][]string{
+		"(document)[weird.key].id": {"Positive"},
+	}, failuresByName(t, err))
+}
+
+func TestWithoutUntyped(t *testing.T) {
+	err := Validate(map[string]interface{}{})
+	require.IsType(t, ErrorInvalidKind{}, err)
+}